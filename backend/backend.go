@@ -11,10 +11,17 @@ import (
 	"embed"
 
 	firebase "firebase.google.com/go"
+	"github.com/faiz-muttaqin/lgs/backend/internal/alert"
 	"github.com/faiz-muttaqin/lgs/backend/internal/database"
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
 	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/mailer"
 	"github.com/faiz-muttaqin/lgs/backend/internal/middleware"
+	"github.com/faiz-muttaqin/lgs/backend/internal/retention"
 	"github.com/faiz-muttaqin/lgs/backend/internal/routes"
+	"github.com/faiz-muttaqin/lgs/backend/internal/semantic"
+	"github.com/faiz-muttaqin/lgs/backend/internal/webhook"
+	"github.com/faiz-muttaqin/lgs/backend/internal/websockets"
 	"github.com/faiz-muttaqin/lgs/backend/pkg/clr"
 	"github.com/faiz-muttaqin/lgs/backend/pkg/docs"
 	"github.com/faiz-muttaqin/lgs/backend/pkg/kvstore"
@@ -39,12 +46,19 @@ const (
 func StartServer(embeddedFiles embed.FS) {
 	isDevMode := util.IsDevMode()
 	database.Init()
+	alert.Start(database.DB)
+	mailer.Start(database.DB)
+	webhook.Start(database.DB)
+	retention.Start(database.DB)
+	semantic.Start(database.DB)
+	events.StartRelay(database.DB) // After all subscribers are registered
 	go func() {
 		kvstore.RDB = kvstore.InitRedis(
 			os.Getenv("REDIS_HOST")+":"+os.Getenv("REDIS_PORT"),
 			os.Getenv("REDIS_PASSWORD"),
 			os.Getenv("REDIS_DB"),
 		)
+		websockets.InitBroker(kvstore.RDB)
 	}()
 	cred := os.Getenv("FIREBASE_PRIVATE_KEY_JSON")
 	opt := option.WithCredentialsJSON([]byte(cred))
@@ -0,0 +1,122 @@
+package alert
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/notification"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Start subscribes the wishlist alert evaluator to product updates
+func Start(db *gorm.DB) {
+	events.On("wishlist_alert", func(e events.ProductUpdated) error {
+		evaluate(db, e.Before, e.After)
+		return nil
+	})
+}
+
+func cooldown() time.Duration {
+	return time.Duration(util.Getenv("WISHLIST_ALERT_COOLDOWN_H", 24)) * time.Hour
+}
+
+func evaluate(db *gorm.DB, before, after model.Product) {
+	// Price went back up: re-arm alerts that were sent for a lower price
+	if after.Price > before.Price {
+		db.Model(&model.WishlistItem{}).
+			Where("product_id = ? AND last_alert_price > 0 AND last_alert_price < ?", after.ID, after.Price).
+			Update("last_alert_price", 0)
+	}
+
+	if after.Price < before.Price {
+		evaluatePriceDrop(db, after)
+	}
+	if before.Stock <= 0 && after.Stock > 0 {
+		evaluateBackInStock(db, after)
+	}
+}
+
+func evaluatePriceDrop(db *gorm.DB, product model.Product) {
+	var items []model.WishlistItem
+	if err := db.Where("product_id = ? AND notify_price_drop = ?", product.ID, true).
+		Find(&items).Error; err != nil {
+		logrus.Errorf("Failed to load wishlist items for product %d: %v", product.ID, err)
+		return
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		if product.Price >= item.PriceThreshold() {
+			continue
+		}
+		if item.LastAlertPrice > 0 && product.Price >= item.LastAlertPrice {
+			continue // Already alerted at this price or lower
+		}
+		if item.PriceAlertedAt != nil && now.Sub(*item.PriceAlertedAt) < cooldown() {
+			continue
+		}
+
+		// Claim the alert atomically so concurrent evaluations don't send it twice
+		res := db.Model(&model.WishlistItem{}).
+			Where("id = ? AND (last_alert_price = 0 OR last_alert_price > ?)", item.ID, product.Price).
+			Updates(map[string]interface{}{
+				"last_alert_price": product.Price,
+				"price_alerted_at": now,
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+
+		title := fmt.Sprintf("Harga turun: %s", product.Name)
+		body := fmt.Sprintf("%s sekarang %s (sebelumnya %s saat kamu simpan).",
+			product.Name, util.FormatIDR(int(product.Price)), util.FormatIDR(int(item.SavedPrice)))
+		deliver(db, item, product, model.NotificationWishlistPriceDrop, title, body)
+	}
+}
+
+func evaluateBackInStock(db *gorm.DB, product model.Product) {
+	var items []model.WishlistItem
+	if err := db.Where("product_id = ? AND notify_back_in_stock = ?", product.ID, true).
+		Find(&items).Error; err != nil {
+		logrus.Errorf("Failed to load wishlist items for product %d: %v", product.ID, err)
+		return
+	}
+
+	now := time.Now()
+	for _, item := range items {
+		res := db.Model(&model.WishlistItem{}).
+			Where("id = ? AND (stock_alerted_at IS NULL OR stock_alerted_at < ?)", item.ID, now.Add(-cooldown())).
+			Update("stock_alerted_at", now)
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+
+		title := fmt.Sprintf("Stok tersedia lagi: %s", product.Name)
+		body := fmt.Sprintf("%s kembali tersedia (%d stok) dengan harga %s.",
+			product.Name, product.Stock, util.FormatIDR(int(product.Price)))
+		deliver(db, item, product, model.NotificationWishlistBackInStock, title, body)
+	}
+}
+
+// deliver sends the alert through the notification center (in-app + email per user preference)
+func deliver(db *gorm.DB, item model.WishlistItem, product model.Product, notificationType, title, body string) {
+	if _, err := notification.Send(db, item.UserID, notification.Message{
+		Type:  notificationType,
+		Title: title,
+		Body:  body,
+		Link:  fmt.Sprintf("/products/%d", product.ID),
+		Payload: map[string]interface{}{
+			"product_id":  product.ID,
+			"wishlist_id": item.ID,
+			"image_url":   product.ImageURL,
+			"price":       product.Price,
+			"stock":       product.Stock,
+		},
+	}); err != nil {
+		logrus.Errorf("Failed to deliver wishlist alert for item %d: %v", item.ID, err)
+	}
+}
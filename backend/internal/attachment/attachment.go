@@ -0,0 +1,168 @@
+package attachment
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const thumbnailSize = 320
+
+// Accepted content types, detected by sniffing the file
+var kinds = map[string]string{
+	"image/jpeg":      model.AttachmentImage,
+	"image/png":       model.AttachmentImage,
+	"image/gif":       model.AttachmentImage,
+	"image/webp":      model.AttachmentImage,
+	"application/pdf": model.AttachmentPDF,
+	"video/mp4":       model.AttachmentVideo,
+	"video/webm":      model.AttachmentVideo,
+	"video/quicktime": model.AttachmentVideo,
+}
+
+// ErrInvalid is returned for files that are rejected by type or size; the message is safe to show users
+var ErrInvalid = errors.New("invalid attachment")
+
+// MaxSize returns the upload limit in bytes for a kind. Videos are capped by size since the
+// server does not decode them to check duration.
+func MaxSize(kind string) int64 {
+	switch kind {
+	case model.AttachmentPDF:
+		return int64(util.Getenv("CHAT_MAX_PDF_MB", 20)) << 20
+	case model.AttachmentVideo:
+		return int64(util.Getenv("CHAT_MAX_VIDEO_MB", 50)) << 20
+	default:
+		return int64(util.Getenv("CHAT_MAX_IMAGE_MB", 10)) << 20
+	}
+}
+
+// Dir returns the private directory where chat attachments are stored
+func Dir() string {
+	if dir := os.Getenv("CHAT_ATTACHMENT_DIR"); dir != "" {
+		return dir
+	}
+	base, err := util.GetAppDataDir(os.Getenv("APP_NAME"))
+	if err != nil {
+		base = "./uploads"
+	}
+	return filepath.Join(base, "chat_attachments")
+}
+
+// Store validates and saves an uploaded file for a chat, generating a thumbnail for images
+func Store(db *gorm.DB, chatID, uploaderID uint, fh *multipart.FileHeader) (*model.ChatAttachment, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	mime, err := util.DetectMIME(file)
+	if err != nil {
+		return nil, err
+	}
+	kind, ok := kinds[mime]
+	if !ok {
+		return nil, fmt.Errorf("%w: file type %s is not allowed, upload an image, PDF or video", ErrInvalid, mime)
+	}
+	if fh.Size > MaxSize(kind) {
+		return nil, fmt.Errorf("%w: %s exceeds the %d MB limit", ErrInvalid, kind, MaxSize(kind)>>20)
+	}
+
+	name, err := util.GenerateSecureToken(18)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(Dir(), strconv.FormatUint(uint64(chatID), 10))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	// Stored under a random name so the original filename never reaches the filesystem
+	path := filepath.Join(dir, name)
+	if err := saveFile(file, path); err != nil {
+		return nil, err
+	}
+
+	a := model.ChatAttachment{
+		ChatID:      chatID,
+		UploaderID:  uploaderID,
+		Kind:        kind,
+		MimeType:    mime,
+		FileName:    sanitizeFileName(fh.Filename),
+		Size:        fh.Size,
+		StoragePath: path,
+	}
+
+	if kind == model.AttachmentImage {
+		if src, err := os.Open(path); err == nil {
+			thumb, w, h, err := util.MakeThumbnail(src, thumbnailSize, util.Getenv("CHAT_THUMBNAIL_MAX_PIXELS", 40_000_000))
+			src.Close()
+			if errors.Is(err, util.ErrImageTooLarge) {
+				// Served without a thumbnail rather than decoded into memory
+				a.Width, a.Height = w, h
+				logrus.Infof("No thumbnail for chat attachment %s: %dx%d is over CHAT_THUMBNAIL_MAX_PIXELS", fh.Filename, w, h)
+			} else if err == nil {
+				a.Width, a.Height = w, h
+				if os.WriteFile(path+"_thumb.jpg", thumb, 0o600) == nil {
+					a.ThumbnailPath = path + "_thumb.jpg"
+				}
+			} else {
+				// WebP has no decoder in the standard library; the original is still served
+				logrus.Debugf("No thumbnail for chat attachment %s: %v", fh.Filename, err)
+			}
+		}
+	}
+
+	if err := db.Create(&a).Error; err != nil {
+		Remove(a)
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Remove deletes an attachment's files from disk
+func Remove(a model.ChatAttachment) {
+	os.Remove(a.StoragePath)
+	if a.ThumbnailPath != "" {
+		os.Remove(a.ThumbnailPath)
+	}
+}
+
+func saveFile(src multipart.File, path string) error {
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := out.ReadFrom(src); err != nil {
+		out.Close()
+		os.Remove(path)
+		return err
+	}
+	return out.Close()
+}
+
+func sanitizeFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == '"' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+	if len(name) > 255 {
+		name = name[len(name)-255:]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
@@ -0,0 +1,109 @@
+package autoreply
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // Shops pick IANA timezones; don't depend on the host having zoneinfo
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/kvstore"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+// Auto-reply kinds, also used as the event of the system message
+const (
+	Greeting = "auto_reply.greeting"
+	Away     = "auto_reply.away"
+)
+
+// DefaultTimezone is used for shops that haven't picked one
+const DefaultTimezone = "Asia/Jakarta"
+
+// Vars are the values substituted into templates
+type Vars struct {
+	BuyerName   string
+	ProductName string
+	ShopName    string
+}
+
+// Render fills the {buyer_name}, {product_name} and {shop_name} placeholders
+func Render(template string, vars Vars) string {
+	return strings.NewReplacer(
+		"{buyer_name}", fallback(vars.BuyerName, "there"),
+		"{product_name}", fallback(vars.ProductName, "the product"),
+		"{shop_name}", vars.ShopName,
+	).Replace(template)
+}
+
+// BuyerName is how a buyer is addressed in templates
+func BuyerName(u model.User) string {
+	if name := strings.TrimSpace(u.FirstName); name != "" {
+		return name
+	}
+	return u.Username
+}
+
+// IsOpen reports whether the shop is within its operating hours at t. A shop without hours is always open.
+func IsOpen(settings model.ShopChatSettings, t time.Time) bool {
+	if len(settings.OperatingHours) == 0 {
+		return true
+	}
+
+	loc, err := time.LoadLocation(fallback(settings.Timezone, DefaultTimezone))
+	if err != nil {
+		loc = time.Local
+	}
+	t = t.In(loc)
+	now := t.Hour()*60 + t.Minute()
+	yesterday := (t.Weekday() + 6) % 7
+
+	for _, h := range settings.OperatingHours {
+		opens, err1 := ParseClock(h.Open)
+		closes, err2 := ParseClock(h.Close)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if opens < closes {
+			if h.Day == t.Weekday() && now >= opens && now < closes {
+				return true
+			}
+			continue
+		}
+		// Overnight window: the evening of Day plus the early hours of the next day
+		if (h.Day == t.Weekday() && now >= opens) || (h.Day == yesterday && now < closes) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseClock converts HH:MM to minutes after midnight
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Due reports whether a reply of this kind may be sent in the chat now, and records it if so.
+// Each kind is sent at most once per CHAT_AUTO_REPLY_COOLDOWN_MIN in a chat so that a buyer sending
+// several messages outside opening hours gets one away message, not one per message.
+func Due(kind string, chatID uint) bool {
+	cooldown := time.Duration(util.Getenv("CHAT_AUTO_REPLY_COOLDOWN_MIN", 60)) * time.Minute
+	n, err := kvstore.IncrKey(fmt.Sprintf("chat:autoreply:%s:%d", kind, chatID), cooldown)
+	if err != nil {
+		logrus.Errorf("Auto-reply cooldown check failed for chat %d: %v", chatID, err)
+		return false
+	}
+	return n == 1
+}
+
+func fallback(s, def string) string {
+	if strings.TrimSpace(s) == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,168 @@
+package chatassist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/llm"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+const (
+	buyerID  = 1
+	sellerID = 2
+)
+
+func setup(t *testing.T) (*gorm.DB, *llm.Fake, model.Chat) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())),
+		&gorm.Config{DisableForeignKeyConstraintWhenMigrating: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.Chat{}, &model.Message{}, &model.ChatAttachment{}, &model.LLMUsage{}); err != nil {
+		t.Fatal(err)
+	}
+
+	fake := llm.NewFake(`["Halo kak, stoknya masih ada", "Siap kak, bisa dikirim hari ini"]`)
+	llm.SetDefault(fake)
+	t.Cleanup(func() { llm.SetDefault(nil) })
+
+	chat := model.Chat{User1ID: buyerID, User2ID: sellerID}
+	if err := db.Create(&chat).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db, fake, chat
+}
+
+func send(t *testing.T, db *gorm.DB, chat model.Chat, senderID uint, content string) model.Message {
+	t.Helper()
+	m := model.Message{ChatID: chat.ID, SenderID: senderID, Kind: model.MessageText, Content: content}
+	if err := db.Create(&m).Error; err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// cost is what conversation charges a message against the budget
+func cost(m model.Message) int {
+	return llm.CountTokens(messageLine(m, buyerID)) + 1
+}
+
+func TestConversationKeepsNewestMessagesWithinBudget(t *testing.T) {
+	db, _, chat := setup(t)
+	send(t, db, chat, buyerID, "Halo, barangnya masih ada?")
+	send(t, db, chat, sellerID, "Masih kak")
+	second := send(t, db, chat, buyerID, "Bisa kirim ke Bandung?")
+	last := send(t, db, chat, buyerID, "Ongkirnya berapa ya?")
+
+	history, n := conversation(db, chat.ID, buyerID, cost(second)+cost(last))
+	if n != 2 {
+		t.Fatalf("used %d messages, want 2", n)
+	}
+	want := "Buyer: Bisa kirim ke Bandung?\nBuyer: Ongkirnya berapa ya?"
+	if history != want {
+		t.Errorf("history = %q, want %q", history, want)
+	}
+}
+
+func TestConversationAlwaysKeepsNewestMessage(t *testing.T) {
+	db, _, chat := setup(t)
+	send(t, db, chat, sellerID, "Ada yang bisa dibantu?")
+	send(t, db, chat, buyerID, strings.Repeat("apakah ukuran ini cocok untuk ruangan kecil ", 50))
+
+	history, n := conversation(db, chat.ID, buyerID, 1)
+	if n != 1 {
+		t.Fatalf("used %d messages, want only the newest", n)
+	}
+	if !strings.HasPrefix(history, "Buyer: apakah ukuran") || !strings.HasSuffix(history, "…") {
+		t.Errorf("newest message not kept in shortened form: %q", history)
+	}
+}
+
+func TestConversationSkipsDeletedAndRedactedMessages(t *testing.T) {
+	db, _, chat := setup(t)
+	send(t, db, chat, buyerID, "Halo")
+	deleted := send(t, db, chat, buyerID, "pesan dihapus")
+	db.Model(&deleted).Update("is_deleted", true)
+	redacted := send(t, db, chat, sellerID, "pesan lama")
+	db.Model(&redacted).Update("redacted_at", gorm.Expr("CURRENT_TIMESTAMP"))
+
+	history, n := conversation(db, chat.ID, buyerID, 1000)
+	if n != 1 || history != "Buyer: Halo" {
+		t.Errorf("conversation = %q (%d messages), want only the visible message", history, n)
+	}
+}
+
+func TestParseDraftsJSON(t *testing.T) {
+	output := "Here are the replies:\n```json\n[\"Halo kak\", \" Halo kak \", \"Stoknya ada\", \"\", \"Bisa COD\"]\n```"
+
+	if got := parseDrafts(output, 3); !slices.Equal(got, []string{"Halo kak", "Stoknya ada", "Bisa COD"}) {
+		t.Errorf("drafts = %q", got)
+	}
+	if got := parseDrafts(output, 1); !slices.Equal(got, []string{"Halo kak"}) {
+		t.Errorf("drafts limited to 1 = %q", got)
+	}
+}
+
+func TestParseDraftsOnePerLine(t *testing.T) {
+	output := "1. Halo kak, stok masih ada\n2) \"Bisa dikirim hari ini\"\n\n- Terima kasih sudah bertanya\n* Halo kak, stok masih ada"
+
+	want := []string{"Halo kak, stok masih ada", "Bisa dikirim hari ini", "Terima kasih sudah bertanya"}
+	if got := parseDrafts(output, MaxDrafts); !slices.Equal(got, want) {
+		t.Errorf("drafts = %q, want %q", got, want)
+	}
+}
+
+func TestParseDraftsKeepsLeadingNumbers(t *testing.T) {
+	output := `["100 ribu sudah termasuk ongkir", "2 warna tersedia: hitam dan putih", "- bisa COD"]`
+
+	want := []string{"100 ribu sudah termasuk ongkir", "2 warna tersedia: hitam dan putih", "- bisa COD"}
+	if got := parseDrafts(output, MaxDrafts); !slices.Equal(got, want) {
+		t.Errorf("drafts = %q, want %q", got, want)
+	}
+
+	// Lines lose only a whole list marker
+	output = "1. 100 ribu sudah termasuk ongkir\n2 warna tersedia\n3.5 kg beratnya"
+	want = []string{"100 ribu sudah termasuk ongkir", "2 warna tersedia", "3.5 kg beratnya"}
+	if got := parseDrafts(output, MaxDrafts); !slices.Equal(got, want) {
+		t.Errorf("line drafts = %q, want %q", got, want)
+	}
+}
+
+func TestDraftWithoutMessages(t *testing.T) {
+	db, fake, chat := setup(t)
+
+	_, err := Draft(context.Background(), db, Request{SellerID: sellerID, Chat: chat, BuyerID: buyerID, Count: 2})
+	if !errors.Is(err, ErrNoMessages) {
+		t.Fatalf("err = %v, want ErrNoMessages", err)
+	}
+	if fake.Calls() != 0 {
+		t.Error("the model was asked without any messages")
+	}
+}
+
+func TestDraft(t *testing.T) {
+	db, fake, chat := setup(t)
+	send(t, db, chat, buyerID, "Kak, masih ada stok?")
+
+	result, err := Draft(context.Background(), db, Request{SellerID: sellerID, Chat: chat, BuyerID: buyerID, Count: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Drafts) != 2 || result.MessagesUsed != 1 || fake.Calls() != 1 {
+		t.Errorf("result = %+v after %d calls", result, fake.Calls())
+	}
+
+	var usage int64
+	db.Model(&model.LLMUsage{}).Where("user_id = ? AND success = ?", sellerID, true).Count(&usage)
+	if usage != 1 {
+		t.Errorf("recorded %d usage rows, want 1", usage)
+	}
+}
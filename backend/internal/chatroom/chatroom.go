@@ -0,0 +1,125 @@
+package chatroom
+
+import (
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotParticipant is returned for chats the user may not access
+var ErrNotParticipant = errors.New("not a participant of this chat")
+
+// Joined is the subquery of chat IDs the user participates in; use as "chat_id IN (?)"
+func Joined(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&model.ChatParticipant{}).Select("chat_id").Where("user_id = ?", userID)
+}
+
+// Find loads a chat the user participates in
+func Find(db *gorm.DB, chatID, userID uint) (model.Chat, error) {
+	var chat model.Chat
+	err := db.Where("id = ? AND id IN (?)", chatID, Joined(db, userID)).First(&chat).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return chat, ErrNotParticipant
+	}
+	return chat, err
+}
+
+// CanAccess reports whether the user participates in the chat
+func CanAccess(db *gorm.DB, chatID, userID uint) bool {
+	var count int64
+	db.Model(&model.ChatParticipant{}).Where("chat_id = ? AND user_id = ?", chatID, userID).Count(&count)
+	return count > 0
+}
+
+// Participants returns everyone in the chat
+func Participants(db *gorm.DB, chatID uint) []model.ChatParticipant {
+	var participants []model.ChatParticipant
+	db.Where("chat_id = ?", chatID).Order("id ASC").Find(&participants)
+	return participants
+}
+
+// Members returns the user IDs of everyone in the chat
+func Members(db *gorm.DB, chatID uint) []uint {
+	var ids []uint
+	db.Model(&model.ChatParticipant{}).Where("chat_id = ?", chatID).Pluck("user_id", &ids)
+	return ids
+}
+
+// Join adds a user to a chat; joining twice keeps the existing role and read state
+func Join(tx *gorm.DB, chatID, userID uint, role string) error {
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&model.ChatParticipant{ChatID: chatID, UserID: userID, Role: role}).Error
+}
+
+// Counterpart is the user a message from senderID is addressed to. In a shop chat the buyer writes to
+// the assigned team member (or the owner) and the team writes to the buyer.
+func Counterpart(db *gorm.DB, chat model.Chat, senderID uint) uint {
+	participants := Participants(db, chat.ID)
+
+	if chat.ShopID != nil {
+		sender := slices.IndexFunc(participants, func(p model.ChatParticipant) bool { return p.UserID == senderID })
+		if sender >= 0 && participants[sender].IsShopTeam() {
+			for _, p := range participants {
+				if p.Role == model.ChatRoleBuyer {
+					return p.UserID
+				}
+			}
+		}
+		if chat.AssignedTo != nil && *chat.AssignedTo != senderID {
+			return *chat.AssignedTo
+		}
+		for _, p := range participants {
+			if p.Role == model.ChatRoleOwner && p.UserID != senderID {
+				return p.UserID
+			}
+		}
+	}
+
+	for _, p := range participants {
+		if p.UserID != senderID {
+			return p.UserID
+		}
+	}
+	// Fall back to the original pair for chats that lost their participants
+	if chat.User1ID == senderID {
+		return chat.User2ID
+	}
+	return chat.User1ID
+}
+
+// Team returns the shop owner and staff user IDs
+func Team(db *gorm.DB, shopID uint) []uint {
+	var team []uint
+	var shop model.Shop
+	if err := db.Select("id", "user_id").First(&shop, shopID).Error; err == nil {
+		team = append(team, shop.UserID)
+	}
+	var staff []uint
+	db.Model(&model.ShopStaff{}).Where("shop_id = ?", shopID).Pluck("user_id", &staff)
+	return append(team, staff...)
+}
+
+// IsTeam reports whether the user owns or works for the shop
+func IsTeam(db *gorm.DB, shopID, userID uint) bool {
+	return slices.Contains(Team(db, shopID), userID)
+}
+
+// MarkRead moves the participant's read marker forward to messageID and reports whether it moved;
+// it never moves backwards
+func MarkRead(tx *gorm.DB, chatID, userID, messageID uint) (bool, error) {
+	res := tx.Model(&model.ChatParticipant{}).
+		Where("chat_id = ? AND user_id = ? AND last_read_message_id < ?", chatID, userID, messageID).
+		Updates(map[string]any{"last_read_message_id": messageID, "last_read_at": time.Now()})
+	return res.RowsAffected > 0, res.Error
+}
+
+// Unread scopes messages to those the user hasn't read yet across their chats
+func Unread(db *gorm.DB, userID uint) *gorm.DB {
+	return db.Model(&model.Message{}).
+		Joins("JOIN chat_participants ON chat_participants.chat_id = messages.chat_id AND chat_participants.user_id = ?", userID).
+		Where("messages.sender_id <> ? AND messages.id > chat_participants.last_read_message_id AND messages.is_deleted = false", userID)
+}
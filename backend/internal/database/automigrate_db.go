@@ -17,14 +17,36 @@ func AutoMigrateDB(db *gorm.DB) error {
 		&model.UserRole{},
 		&model.UserAbilityRule{},
 		&model.User{},
+		&model.WishlistCollection{},
 		&model.WishlistItem{},
 		&model.Chat{},
 		&model.Message{},
+		&model.ChatParticipant{},
 		&model.Shop{},
+		&model.ShopStaff{},
+		&model.Notification{},
+		&model.NotificationPreference{},
+		&model.EmailOutbox{},
+		&model.EventOutbox{},
+		&model.WebhookEndpoint{},
+		&model.WebhookDelivery{},
+		&model.ChatAttachment{},
+		&model.ChatOffer{},
+		&model.SpecialPrice{},
+		&model.UserBlock{},
+		&model.ChatReport{},
+		&model.ShopChatSettings{},
+		&model.QuickReply{},
+		&model.LLMUsage{},
+		&model.ProductEmbedding{},
 	); err != nil {
 		return err
 	}
 
+	if err := migrateChatParticipants(db); err != nil {
+		return err
+	}
+
 	// Pastikan role default tersedia
 	db.FirstOrCreate(&model.UserRole{
 		ID:    1,
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrateChatParticipants backfills chat_participants for chats created when chats were strictly
+// one-to-one. A chat with a shop owner becomes a shop chat (buyer + owner + staff); other chats get two
+// members. Read markers are taken from the messages each user had already read. Chats that already have
+// participants are skipped, so this is safe to run on every start.
+func migrateChatParticipants(db *gorm.DB) error {
+	var chats []model.Chat
+	migrated := 0
+	err := db.Where("id NOT IN (?)", db.Model(&model.ChatParticipant{}).Select("chat_id")).
+		FindInBatches(&chats, 200, func(tx *gorm.DB, batch int) error {
+			for _, chat := range chats {
+				if err := db.Transaction(func(tx *gorm.DB) error {
+					return migrateChat(tx, chat)
+				}); err != nil {
+					return fmt.Errorf("chat %d: %w", chat.ID, err)
+				}
+				migrated++
+			}
+			return nil
+		}).Error
+	if migrated > 0 {
+		logrus.Infof("Migrated %d chats to chat participants", migrated)
+	}
+	return err
+}
+
+func migrateChat(tx *gorm.DB, chat model.Chat) error {
+	shop, found := chatShop(tx, chat)
+
+	roles := map[uint]string{chat.User1ID: model.ChatRoleMember, chat.User2ID: model.ChatRoleMember}
+	if found {
+		for userID := range roles {
+			if userID == shop.UserID {
+				roles[userID] = model.ChatRoleOwner
+			} else {
+				roles[userID] = model.ChatRoleBuyer
+			}
+		}
+		if err := tx.Model(&chat).Update("shop_id", shop.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	for userID, role := range roles {
+		var lastRead uint
+		tx.Model(&model.Message{}).
+			Where("chat_id = ? AND receiver_id = ? AND read_at IS NOT NULL", chat.ID, userID).
+			Select("COALESCE(MAX(id), 0)").Scan(&lastRead)
+
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ChatParticipant{
+			ChatID:            chat.ID,
+			UserID:            userID,
+			Role:              role,
+			LastReadMessageID: lastRead,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if found {
+		var staff []uint
+		tx.Model(&model.ShopStaff{}).Where("shop_id = ?", shop.ID).Pluck("user_id", &staff)
+		for _, userID := range staff {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model.ChatParticipant{
+				ChatID: chat.ID,
+				UserID: userID,
+				Role:   model.ChatRoleStaff,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// chatShop finds the shop a legacy chat was addressed to: the shop of the product it was started from,
+// otherwise the shop owned by the contacted user, otherwise one owned by the starter
+func chatShop(tx *gorm.DB, chat model.Chat) (model.Shop, bool) {
+	var shop model.Shop
+	if chat.ProductID.Valid {
+		if err := tx.Joins("JOIN products ON products.shop_id = shops.id").
+			Where("products.id = ? AND shops.user_id IN ?", chat.ProductID.Int64, []uint{chat.User1ID, chat.User2ID}).
+			First(&shop).Error; err == nil {
+			return shop, true
+		}
+	}
+	for _, userID := range []uint{chat.User2ID, chat.User1ID} {
+		if err := tx.Where("user_id = ?", userID).First(&shop).Error; err == nil {
+			return shop, true
+		}
+	}
+	return shop, false
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Envelope is what subscribers receive
+type Envelope struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	AggregateID string          `json:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// Handler processes an event; returning an error makes the relay retry it later.
+// Delivery is at-least-once, so handlers must be idempotent.
+type Handler func(env Envelope) error
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+var (
+	subscribers = map[string][]subscriber{}
+	subMutex    sync.RWMutex
+	wake        = make(chan struct{}, 1)
+)
+
+// Publish writes the event to the outbox using tx, so it commits or rolls back together with the change.
+// Pass the transaction used for the write, not the global DB.
+func Publish(tx *gorm.DB, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode %s event: %w", event.EventType(), err)
+	}
+
+	row := model.EventOutbox{
+		EventID:       uuid.New().String(),
+		Type:          event.EventType(),
+		AggregateID:   event.AggregateID(),
+		Payload:       payload,
+		Status:        model.EventStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := tx.Create(&row).Error; err != nil {
+		return fmt.Errorf("store %s event: %w", event.EventType(), err)
+	}
+
+	// Nudge the relay; if the transaction hasn't committed yet the next poll picks it up
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Subscribe registers a named handler for an event type.
+// The name identifies the subscriber in the outbox so retries skip handlers that already succeeded.
+func Subscribe(eventType, name string, handler Handler) {
+	subMutex.Lock()
+	defer subMutex.Unlock()
+
+	for _, s := range subscribers[eventType] {
+		if s.name == name {
+			panic(fmt.Sprintf("events: subscriber %q already registered for %s", name, eventType))
+		}
+	}
+	subscribers[eventType] = append(subscribers[eventType], subscriber{name: name, handler: handler})
+}
+
+// On registers a typed handler, decoding the payload into T
+func On[T Event](name string, fn func(event T) error) {
+	var zero T
+	Subscribe(zero.EventType(), name, func(env Envelope) error {
+		var event T
+		if err := json.Unmarshal(env.Payload, &event); err != nil {
+			return fmt.Errorf("decode %s event: %w", env.Type, err)
+		}
+		return fn(event)
+	})
+}
+
+func subscribersFor(eventType string) []subscriber {
+	subMutex.RLock()
+	defer subMutex.RUnlock()
+	return slices.Clone(subscribers[eventType])
+}
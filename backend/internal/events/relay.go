@@ -0,0 +1,156 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const maxAttempts = 10
+
+// StartRelay runs the goroutine that dispatches outbox events to subscribers.
+// Register subscribers before calling it.
+func StartRelay(db *gorm.DB) {
+	interval := time.Duration(util.Getenv("EVENT_RELAY_INTERVAL_MS", 1000)) * time.Millisecond
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-wake:
+				// Give the publishing transaction a moment to commit
+				time.Sleep(50 * time.Millisecond)
+			}
+			dispatchDue(db)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			pruneProcessed(db)
+			<-ticker.C
+		}
+	}()
+}
+
+// pruneProcessed deletes events processed more than EVENT_OUTBOX_RETENTION_DAYS ago (0 keeps them).
+// Payloads are full copies of what changed, e.g. the text of chat messages, so they must not pile up.
+func pruneProcessed(db *gorm.DB) {
+	days := util.Getenv("EVENT_OUTBOX_RETENTION_DAYS", 7)
+	if days <= 0 {
+		return
+	}
+	res := db.Where("status = ? AND processed_at < ?", model.EventStatusProcessed, time.Now().AddDate(0, 0, -days)).
+		Delete(&model.EventOutbox{})
+	if res.Error != nil {
+		logrus.Errorf("Failed to prune processed events: %v", res.Error)
+	} else if res.RowsAffected > 0 {
+		logrus.Infof("Pruned %d processed events", res.RowsAffected)
+	}
+}
+
+func dispatchDue(db *gorm.DB) {
+	// Events claimed by an instance that died mid-dispatch
+	db.Model(&model.EventOutbox{}).
+		Where("status = ? AND locked_until < ?", model.EventStatusProcessing, time.Now()).
+		Update("status", model.EventStatusPending)
+
+	var due []model.EventOutbox
+	if err := db.Where("status = ? AND next_attempt_at <= ?", model.EventStatusPending, time.Now()).
+		Order("id ASC").
+		Limit(100).
+		Find(&due).Error; err != nil {
+		return // Table may not be migrated yet
+	}
+
+	lease := time.Duration(util.Getenv("EVENT_RELAY_LEASE_S", 300)) * time.Second
+	for _, row := range due {
+		// Claim the row so other instances don't run the same subscribers
+		res := db.Model(&model.EventOutbox{}).
+			Where("id = ? AND status = ?", row.ID, model.EventStatusPending).
+			Updates(map[string]interface{}{
+				"status":       model.EventStatusProcessing,
+				"locked_until": time.Now().Add(lease),
+			})
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		dispatch(db, row)
+	}
+}
+
+func dispatch(db *gorm.DB, row model.EventOutbox) {
+	env := Envelope{
+		ID:          row.EventID,
+		Type:        row.Type,
+		AggregateID: row.AggregateID,
+		OccurredAt:  row.CreatedAt,
+		Payload:     json.RawMessage(row.Payload),
+	}
+
+	var delivered []string
+	if len(row.DeliveredTo) > 0 {
+		json.Unmarshal(row.DeliveredTo, &delivered)
+	}
+
+	var failures []string
+	for _, s := range subscribersFor(row.Type) {
+		if slices.Contains(delivered, s.name) {
+			continue
+		}
+		if err := safeHandle(s, env); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", s.name, err))
+			continue
+		}
+		delivered = append(delivered, s.name)
+	}
+
+	deliveredJSON, _ := json.Marshal(delivered)
+	if len(failures) == 0 {
+		now := time.Now()
+		db.Model(&row).Updates(map[string]interface{}{
+			"status":       model.EventStatusProcessed,
+			"processed_at": now,
+			"locked_until": nil,
+			"delivered_to": deliveredJSON,
+			"last_error":   "",
+		})
+		return
+	}
+
+	row.Attempts++
+	status := model.EventStatusPending
+	if row.Attempts >= maxAttempts {
+		status = model.EventStatusFailed
+	}
+	logrus.Warnf("Event %s (%s) attempt %d failed: %s", row.EventID, row.Type, row.Attempts, strings.Join(failures, "; "))
+	db.Model(&row).Updates(map[string]interface{}{
+		"status":          status,
+		"attempts":        row.Attempts,
+		"next_attempt_at": time.Now().Add(time.Duration(1<<min(row.Attempts, 12)) * time.Second),
+		"locked_until":    nil,
+		"delivered_to":    deliveredJSON,
+		"last_error":      strings.Join(failures, "; "),
+	})
+}
+
+// safeHandle runs a subscriber, turning a panic into an error so one bad handler can't kill the relay
+func safeHandle(s subscriber, env Envelope) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return s.handler(env)
+}
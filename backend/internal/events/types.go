@@ -0,0 +1,63 @@
+package events
+
+import (
+	"fmt"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+)
+
+// Event type names
+const (
+	TypeProductCreated = "product.created"
+	TypeProductUpdated = "product.updated"
+	TypeMessageSent    = "message.sent"
+	TypeShopCreated    = "shop.created"
+	TypeUserRegistered = "user.registered"
+)
+
+// Event is implemented by every domain event
+type Event interface {
+	EventType() string
+	AggregateID() string
+}
+
+// ProductCreated is published when a seller creates a product
+type ProductCreated struct {
+	Product model.Product `json:"product"`
+}
+
+func (ProductCreated) EventType() string     { return TypeProductCreated }
+func (e ProductCreated) AggregateID() string { return fmt.Sprint(e.Product.ID) }
+
+// ProductUpdated is published when a product changes, with snapshots before and after
+type ProductUpdated struct {
+	Before model.Product `json:"before"`
+	After  model.Product `json:"after"`
+}
+
+func (ProductUpdated) EventType() string     { return TypeProductUpdated }
+func (e ProductUpdated) AggregateID() string { return fmt.Sprint(e.After.ID) }
+
+// MessageSent is published when a chat message is stored
+type MessageSent struct {
+	Message model.Message `json:"message"`
+}
+
+func (MessageSent) EventType() string     { return TypeMessageSent }
+func (e MessageSent) AggregateID() string { return fmt.Sprint(e.Message.ChatID) }
+
+// ShopCreated is published when a user opens a shop
+type ShopCreated struct {
+	Shop model.Shop `json:"shop"`
+}
+
+func (ShopCreated) EventType() string     { return TypeShopCreated }
+func (e ShopCreated) AggregateID() string { return fmt.Sprint(e.Shop.ID) }
+
+// UserRegistered is published when a user signs in for the first time
+type UserRegistered struct {
+	User model.User `json:"user"`
+}
+
+func (UserRegistered) EventType() string     { return TypeUserRegistered }
+func (e UserRegistered) AggregateID() string { return fmt.Sprint(e.User.ID) }
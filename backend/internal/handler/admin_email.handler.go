@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/mailer"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetEmailTemplates - Admin: list available email templates and languages
+func GetEmailTemplates() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireSuperAdmin(c); !ok {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"templates": mailer.TemplateNames(),
+				"languages": mailer.Languages,
+			},
+		})
+	}
+}
+
+// PreviewEmailTemplate - Admin: render a template with sample data (?lang=id|en, ?format=json)
+func PreviewEmailTemplate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireSuperAdmin(c); !ok {
+			return
+		}
+
+		name := c.Param("name")
+		lang := c.DefaultQuery("lang", mailer.DefaultLang())
+
+		subject, body, err := mailer.Render(name, lang, mailer.SampleData[name])
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Template not found",
+				"error":   err.Error(),
+			})
+			return
+		}
+
+		if c.Query("format") == "json" {
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"data": gin.H{
+					"subject": subject,
+					"html":    body,
+				},
+			})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(body))
+	}
+}
+
+// GetEmailOutbox - Admin: list queued/sent/failed emails (?status=failed)
+func GetEmailOutbox(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireSuperAdmin(c); !ok {
+			return
+		}
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if page < 1 {
+			page = 1
+		}
+
+		query := db.Model(&model.EmailOutbox{})
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var total int64
+		query.Count(&total)
+
+		var emails []model.EmailOutbox
+		query.Omit("body_html").Order("id DESC").Limit(limit).Offset((page - 1) * limit).Find(&emails)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"emails": emails,
+				"total":  total,
+				"page":   page,
+				"limit":  limit,
+			},
+		})
+	}
+}
+
+// RetryEmailOutbox - Admin: re-queue a permanently failed email
+func RetryEmailOutbox(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, ok := requireSuperAdmin(c)
+		if !ok {
+			return
+		}
+
+		id, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err := mailer.Retry(db, uint(id)); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Update("email_outbox", id).Success("Email re-queued"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Email re-queued",
+		})
+	}
+}
+
+// requireSuperAdmin authenticates the request and writes 401/403 unless the user is a super admin
+func requireSuperAdmin(c *gin.Context) (*model.User, bool) {
+	userData, err := helper.GetFirebaseUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
+		return nil, false
+	}
+	if userData.RoleID != 1 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Admin access required",
+		})
+		return nil, false
+	}
+	return userData, true
+}
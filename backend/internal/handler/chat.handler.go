@@ -1,12 +1,20 @@
 package handler
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
+	"github.com/faiz-muttaqin/lgs/backend/internal/autoreply"
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
 	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
 	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/moderation"
+	"github.com/faiz-muttaqin/lgs/backend/internal/websockets"
 	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -34,10 +42,12 @@ func GetMyChats(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var chats []model.Chat
-		if err := db.Where("user1_id = ? OR user2_id = ?", userData.ID, userData.ID).
+		if err := db.Where("id IN (?)", chatroom.Joined(db, userData.ID)).
 			Preload("User1").
 			Preload("User2").
 			Preload("Product").
+			Preload("Shop").
+			Preload("Participants.User").
 			Order("updated_at DESC").
 			Find(&chats).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -47,30 +57,7 @@ func GetMyChats(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Populate virtual fields for each chat
-		for i := range chats {
-			// Get last message
-			var lastMsg model.Message
-			if err := db.Where("chat_id = ?", chats[i].ID).
-				Order("created_at DESC").
-				First(&lastMsg).Error; err == nil {
-				chats[i].LastMessage = &lastMsg
-			}
-
-			// Count unread messages
-			var unreadCount int64
-			db.Model(&model.Message{}).
-				Where("chat_id = ? AND receiver_id = ? AND read_at IS NULL", chats[i].ID, userData.ID).
-				Count(&unreadCount)
-			chats[i].UnreadCount = int(unreadCount)
-
-			// Set other user
-			if chats[i].User1ID == userData.ID {
-				chats[i].OtherUser = &chats[i].User2
-			} else {
-				chats[i].OtherUser = &chats[i].User1
-			}
-		}
+		populateChats(db, chats, userData.ID)
 
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -96,32 +83,56 @@ func GetOrCreateChat(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var input struct {
-			OtherUserID uint  `json:"other_user_id" binding:"required"`
+			OtherUserID uint  `json:"other_user_id"`
+			ShopID      *uint `json:"shop_id"` // Write to a shop's inbox
 			ProductID   *uint `json:"product_id"`
 		}
 
-		if err := c.ShouldBindJSON(&input); err != nil {
+		if err := c.ShouldBindJSON(&input); err != nil || (input.OtherUserID == 0 && input.ShopID == nil) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"message": "Invalid input",
+				"message": "Invalid input. Provide other_user_id or shop_id",
 			})
 			return
 		}
 
+		// Chats with a shop owner go to the shop's shared inbox
+		var shop *model.Shop
+		var target model.Shop
+		if input.ShopID != nil {
+			if err := db.First(&target, *input.ShopID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"message": "Shop not found",
+				})
+				return
+			}
+			shop = &target
+			input.OtherUserID = target.UserID
+		} else if err := db.Where("user_id = ?", input.OtherUserID).First(&target).Error; err == nil {
+			shop = &target
+		}
+
 		// Don't allow chat with self
-		if input.OtherUserID == userData.ID {
+		if input.OtherUserID == userData.ID || (shop != nil && chatroom.IsTeam(db, shop.ID, userData.ID)) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"message": "Cannot create chat with yourself",
+				"message": "Cannot create chat with yourself or your own shop",
 			})
 			return
 		}
 
 		// Check if chat already exists
 		var chat model.Chat
-		err = db.Where("(user1_id = ? AND user2_id = ?) OR (user1_id = ? AND user2_id = ?)",
-			userData.ID, input.OtherUserID, input.OtherUserID, userData.ID).
-			First(&chat).Error
+		if shop != nil {
+			err = db.Where("shop_id = ? AND id IN (?)", shop.ID,
+				chatroom.Joined(db, userData.ID).Where("role = ?", model.ChatRoleBuyer)).
+				First(&chat).Error
+		} else {
+			err = db.Where("shop_id IS NULL AND id IN (?) AND id IN (?)",
+				chatroom.Joined(db, userData.ID), chatroom.Joined(db, input.OtherUserID)).
+				First(&chat).Error
+		}
 
 		if err == gorm.ErrRecordNotFound {
 			// Create new chat
@@ -133,8 +144,23 @@ func GetOrCreateChat(db *gorm.DB) gin.HandlerFunc {
 				chat.ProductID.Int64 = int64(*input.ProductID)
 				chat.ProductID.Valid = true
 			}
+			if shop != nil {
+				chat.ShopID = &shop.ID
+			}
+			if moderation.IsChatBlocked(db, chat, userData.ID) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"success": false,
+					"message": "You cannot start a chat with this user",
+				})
+				return
+			}
 
-			if err := db.Create(&chat).Error; err != nil {
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Create(&chat).Error; err != nil {
+					return err
+				}
+				return joinChat(tx, chat)
+			}); err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{
 					"success": false,
 					"message": "Failed to create chat",
@@ -143,21 +169,19 @@ func GetOrCreateChat(db *gorm.DB) gin.HandlerFunc {
 			}
 
 			audit.Log(c, db, userData.ID, audit.Create("chat", chat.ID).After(chat).Success("Chat created"))
+
+			sendAutoReply(db, chat, userData.ID, autoreply.Greeting)
 		}
 
 		// Load relations
-		db.Preload("User1").Preload("User2").Preload("Product").First(&chat, chat.ID)
+		db.Preload("User1").Preload("User2").Preload("Product").Preload("Shop").Preload("Participants.User").First(&chat, chat.ID)
 
-		// Set other user
-		if chat.User1ID == userData.ID {
-			chat.OtherUser = &chat.User2
-		} else {
-			chat.OtherUser = &chat.User1
-		}
+		chats := []model.Chat{chat}
+		populateChats(db, chats, userData.ID)
 
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
-			"data":    chat,
+			"data":    chats[0],
 		})
 	}
 
@@ -176,34 +200,58 @@ func GetChatMessages(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		chatID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
-
 		// Verify user is part of the chat
-		var chat model.Chat
-		if err := db.Where("id = ? AND (user1_id = ? OR user2_id = ?)", chatID, userData.ID, userData.ID).
-			First(&chat).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "Chat not found",
-			})
+		chat, ok := findMyChat(c, db, userData.ID)
+		if !ok {
 			return
 		}
 
-		// Get pagination params
-		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
-		offset := (page - 1) * limit
+		// Cursor pagination by ID: IDs only grow, so pages stay stable while new messages arrive.
+		// before_id pages back through history, after_id fetches newer messages, and around_id
+		// returns a window centred on one message for jump-to-message.
+		limit := messageLimit(c)
+		chatMessages := func() *gorm.DB {
+			return db.Where("messages.chat_id = ? AND messages.is_deleted = false", chat.ID)
+		}
 
 		var messages []model.Message
-		if err := db.Where("chat_id = ? AND is_deleted = false", chatID).
-			Preload("Sender").
-			Preload("Receiver").
-			Preload("ReplyToMessage").
-			Preload("ReplyToMessage.Sender").
-			Order("created_at DESC").
-			Limit(limit).
-			Offset(offset).
-			Find(&messages).Error; err != nil {
+		var hasOlder, hasNewer bool
+		switch {
+		case c.Query("around_id") != "":
+			aroundID, _ := strconv.ParseUint(c.Query("around_id"), 10, 32)
+			var target model.Message
+			if err := chatMessages().Where("messages.id = ?", aroundID).First(&target).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"message": "Message not found",
+				})
+				return
+			}
+			// The target itself is the newest entry of the older half
+			older, more, err := messagesBefore(chatMessages(), target.ID+1, limit/2+1)
+			if err == nil {
+				hasOlder = more
+				var newer []model.Message
+				newer, hasNewer, err = messagesAfter(chatMessages(), target.ID, limit-len(older))
+				messages = append(newer, older...)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"message": "Failed to retrieve messages",
+				})
+				return
+			}
+		case c.Query("after_id") != "":
+			afterID, _ := strconv.ParseUint(c.Query("after_id"), 10, 32)
+			messages, hasNewer, err = messagesAfter(chatMessages(), uint(afterID), limit)
+			hasOlder = afterID > 0
+		default:
+			beforeID, _ := strconv.ParseUint(c.Query("before_id"), 10, 32)
+			messages, hasOlder, err = messagesBefore(chatMessages(), uint(beforeID), limit)
+			hasNewer = beforeID > 0
+		}
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
 				"message": "Failed to retrieve messages",
@@ -211,18 +259,9 @@ func GetChatMessages(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Count total messages
-		var total int64
-		db.Model(&model.Message{}).Where("chat_id = ? AND is_deleted = false", chatID).Count(&total)
-
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
-			"data": gin.H{
-				"messages": messages,
-				"total":    total,
-				"page":     page,
-				"limit":    limit,
-			},
+			"data":    messagePage(messages, limit, hasOlder, hasNewer),
 		})
 	}
 
@@ -241,49 +280,86 @@ func SendMessage(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		chatID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
-
 		// Verify user is part of the chat
-		var chat model.Chat
-		if err := db.Where("id = ? AND (user1_id = ? OR user2_id = ?)", chatID, userData.ID, userData.ID).
-			First(&chat).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "Chat not found",
-			})
+		chat, ok := findMyChat(c, db, userData.ID)
+		if !ok {
 			return
 		}
 
 		var input struct {
-			Content          string `json:"content" binding:"required"`
-			AttachmentURL    string `json:"attachment_url"`
-			AttachmentType   string `json:"attachment_type"`
+			Content          string `json:"content"`
+			AttachmentID     *uint  `json:"attachment_id"` // From POST /chats/:id/attachments
+			ProductID        *uint  `json:"product_id"`    // Share a product card
 			ReplyToMessageID *uint  `json:"reply_to_message_id"`
 		}
 
-		if err := c.ShouldBindJSON(&input); err != nil {
+		if err := c.ShouldBindJSON(&input); err != nil || (input.Content == "" && input.AttachmentID == nil && input.ProductID == nil) {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
-				"message": "Invalid input",
+				"message": "Invalid input. Provide content, attachment_id and/or product_id",
 			})
 			return
 		}
 
-		// Determine receiver
-		var receiverID uint
-		if chat.User1ID == userData.ID {
-			receiverID = chat.User2ID
-		} else {
-			receiverID = chat.User1ID
+		// Attachments must have been uploaded to this chat by the sender and not used yet
+		var attachment *model.ChatAttachment
+		if input.AttachmentID != nil {
+			var a model.ChatAttachment
+			if err := db.Where("id = ? AND chat_id = ? AND uploader_id = ? AND message_id IS NULL", *input.AttachmentID, chat.ID, userData.ID).
+				First(&a).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Attachment not found or already sent",
+				})
+				return
+			}
+			attachment = &a
+		}
+
+		var card *model.ProductCardPayload
+		if input.ProductID != nil {
+			var product model.Product
+			if err := db.Where("id = ? AND is_active = ?", *input.ProductID, true).First(&product).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Product not found",
+				})
+				return
+			}
+			pc := product.ProductCard()
+			card = &pc
+		}
+
+		// Determine receiver; in shop chats that is the buyer or the team member handling the chat
+		receiverID := chatroom.Counterpart(db, chat, userData.ID)
+
+		if !canMessage(c, db, userData, chat) {
+			return
+		}
+		verdict, ok := screenContent(c, input.Content)
+		if !ok {
+			return
 		}
 
 		message := model.Message{
-			ChatID:         uint(chatID),
-			SenderID:       userData.ID,
-			ReceiverID:     receiverID,
-			Content:        input.Content,
-			AttachmentURL:  input.AttachmentURL,
-			AttachmentType: input.AttachmentType,
+			ChatID:     chat.ID,
+			SenderID:   userData.ID,
+			ReceiverID: receiverID,
+			Kind:       model.MessageText,
+			Content:    input.Content,
+			IsFlagged:  verdict.Action == moderation.Flag,
+		}
+		if card != nil {
+			message.Kind = model.MessageProductCard
+			message.Payload, _ = json.Marshal(card)
+			if message.Content == "" {
+				message.Content = card.Name
+			}
+		}
+		if attachment != nil {
+			message.AttachmentID = &attachment.ID
+			message.AttachmentURL = attachment.DownloadPath()
+			message.AttachmentType = attachment.Kind
 		}
 
 		if input.ReplyToMessageID != nil {
@@ -291,7 +367,33 @@ func SendMessage(db *gorm.DB) gin.HandlerFunc {
 			message.ReplyToMessageID.Valid = true
 		}
 
-		if err := db.Create(&message).Error; err != nil {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&message).Error; err != nil {
+				return err
+			}
+			if attachment != nil {
+				res := tx.Model(&model.ChatAttachment{}).
+					Where("id = ? AND message_id IS NULL", attachment.ID).
+					Update("message_id", message.ID)
+				if res.Error != nil {
+					return res.Error
+				}
+				if res.RowsAffected == 0 {
+					return fmt.Errorf("attachment %d was already sent", attachment.ID)
+				}
+			}
+			if message.IsFlagged {
+				report := flaggedReport(message, verdict)
+				if err := tx.Create(&report).Error; err != nil {
+					return err
+				}
+			}
+			// Update chat's updated_at
+			if err := tx.Model(&chat).Update("updated_at", time.Now()).Error; err != nil {
+				return err
+			}
+			return events.Publish(tx, events.MessageSent{Message: message})
+		}); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
 				"message": "Failed to send message",
@@ -299,18 +401,21 @@ func SendMessage(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		// Update chat's updated_at
-		db.Model(&chat).Update("updated_at", time.Now())
-
 		// Load relations
 		db.Preload("Sender").
 			Preload("Receiver").
 			Preload("ReplyToMessage").
 			Preload("ReplyToMessage.Sender").
+			Preload("Attachment").
 			First(&message, message.ID)
 
 		audit.Log(c, db, userData.ID, audit.Create("message", message.ID).After(message).Success("Message sent"))
 
+		websockets.PushChatMessage(db, websockets.EventMessageNew, message)
+
+		// Sellers outside their operating hours answer with their away message
+		sendAutoReply(db, chat, userData.ID, autoreply.Away)
+
 		c.JSON(http.StatusCreated, gin.H{
 			"success": true,
 			"message": "Message sent",
@@ -335,8 +440,10 @@ func MarkMessageReceived(db *gorm.DB) gin.HandlerFunc {
 
 		messageID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 
+		// Any participant other than the sender can acknowledge a message
 		var message model.Message
-		if err := db.Where("id = ? AND receiver_id = ?", messageID, userData.ID).First(&message).Error; err != nil {
+		if err := db.Where("id = ? AND sender_id <> ? AND chat_id IN (?)", messageID, userData.ID, chatroom.Joined(db, userData.ID)).
+			First(&message).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{
 				"success": false,
 				"message": "Message not found",
@@ -348,6 +455,13 @@ func MarkMessageReceived(db *gorm.DB) gin.HandlerFunc {
 			now := time.Now()
 			message.ReceivedAt = &now
 			db.Save(&message)
+
+			websockets.PushReceipt(db, websockets.EventMessageDelivered, websockets.ReceiptPayload{
+				ChatID:     message.ChatID,
+				MessageIDs: []uint{message.ID},
+				By:         userData.ID,
+				At:         now,
+			})
 		}
 
 		c.JSON(http.StatusOK, gin.H{
@@ -373,8 +487,10 @@ func MarkMessageRead(db *gorm.DB) gin.HandlerFunc {
 
 		messageID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 
+		// Any participant other than the sender can acknowledge a message
 		var message model.Message
-		if err := db.Where("id = ? AND receiver_id = ?", messageID, userData.ID).First(&message).Error; err != nil {
+		if err := db.Where("id = ? AND sender_id <> ? AND chat_id IN (?)", messageID, userData.ID, chatroom.Joined(db, userData.ID)).
+			First(&message).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{
 				"success": false,
 				"message": "Message not found",
@@ -382,14 +498,23 @@ func MarkMessageRead(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// read_at records the first read; each participant's own progress is their read marker
+		now := time.Now()
 		if message.ReadAt == nil {
-			now := time.Now()
 			message.ReadAt = &now
 			if message.ReceivedAt == nil {
 				message.ReceivedAt = &now
 			}
 			db.Save(&message)
 		}
+		if moved, _ := chatroom.MarkRead(db, message.ChatID, userData.ID, message.ID); moved {
+			websockets.PushReceipt(db, websockets.EventMessageRead, websockets.ReceiptPayload{
+				ChatID:     message.ChatID,
+				MessageIDs: []uint{message.ID},
+				By:         userData.ID,
+				At:         now,
+			})
+		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -412,26 +537,34 @@ func MarkChatRead(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
-		chatID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
-
 		// Verify user is part of the chat
-		var chat model.Chat
-		if err := db.Where("id = ? AND (user1_id = ? OR user2_id = ?)", chatID, userData.ID, userData.ID).
-			First(&chat).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{
-				"success": false,
-				"message": "Chat not found",
-			})
+		chat, ok := findMyChat(c, db, userData.ID)
+		if !ok {
 			return
 		}
 
+		var unreadIDs []uint
+		chatroom.Unread(db, userData.ID).
+			Where("messages.chat_id = ?", chat.ID).
+			Pluck("messages.id", &unreadIDs)
+
 		now := time.Now()
-		db.Model(&model.Message{}).
-			Where("chat_id = ? AND receiver_id = ? AND read_at IS NULL", chatID, userData.ID).
-			Updates(map[string]interface{}{
-				"read_at":     now,
-				"received_at": now,
-			})
+		if len(unreadIDs) > 0 {
+			db.Model(&model.Message{}).
+				Where("id IN ? AND read_at IS NULL", unreadIDs).
+				Updates(map[string]interface{}{
+					"read_at":     now,
+					"received_at": now,
+				})
+			chatroom.MarkRead(db, chat.ID, userData.ID, slices.Max(unreadIDs))
+		}
+
+		websockets.PushReceipt(db, websockets.EventMessageRead, websockets.ReceiptPayload{
+			ChatID:     chat.ID,
+			MessageIDs: unreadIDs,
+			By:         userData.ID,
+			At:         now,
+		})
 
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -465,6 +598,15 @@ func EditMessage(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Cards and offers carry structured payloads and can't be rewritten
+		if message.Kind != "" && message.Kind != model.MessageText {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Only text messages can be edited",
+			})
+			return
+		}
+
 		// Check if can edit
 		timeSinceSent := time.Since(message.CreatedAt)
 		if timeSinceSent > 7*time.Minute {
@@ -495,11 +637,26 @@ func EditMessage(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		verdict, ok := screenContent(c, input.Content)
+		if !ok {
+			return
+		}
+
 		oldMessage := message
 		message.Content = input.Content
 		message.IsEdited = true
+		message.IsFlagged = message.IsFlagged || verdict.Action == moderation.Flag
 
-		if err := db.Save(&message).Error; err != nil {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&message).Error; err != nil {
+				return err
+			}
+			if verdict.Action == moderation.Flag {
+				report := flaggedReport(message, verdict)
+				return tx.Create(&report).Error
+			}
+			return nil
+		}); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
 				"message": "Failed to edit message",
@@ -509,6 +666,8 @@ func EditMessage(db *gorm.DB) gin.HandlerFunc {
 
 		audit.Log(c, db, userData.ID, audit.Update("message", message.ID).Before(oldMessage).After(message).Success("Message edited"))
 
+		websockets.PushChatMessage(db, websockets.EventMessageEdited, message)
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"message": "Message edited",
@@ -541,6 +700,15 @@ func DeleteMessage(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// Offers stay in the history so the negotiation remains auditable
+		if message.Kind == model.MessageOffer || message.Kind == model.MessageSystem {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Offers and notices cannot be deleted",
+			})
+			return
+		}
+
 		// Check if can delete
 		timeSinceSent := time.Since(message.CreatedAt)
 		if timeSinceSent > 7*time.Minute {
@@ -565,6 +733,8 @@ func DeleteMessage(db *gorm.DB) gin.HandlerFunc {
 
 		audit.Log(c, db, userData.ID, audit.Delete("message", message.ID).Before(message).Success("Message deleted"))
 
+		websockets.PushMessageDeleted(db, message)
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"message": "Message deleted",
@@ -587,9 +757,7 @@ func GetUnreadCount(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var count int64
-		db.Model(&model.Message{}).
-			Where("receiver_id = ? AND read_at IS NULL", userData.ID).
-			Count(&count)
+		chatroom.Unread(db, userData.ID).Count(&count)
 
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -615,10 +783,10 @@ func GetUnreadMessages(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var messages []model.Message
-		if err := db.Where("receiver_id = ? AND read_at IS NULL AND is_deleted = false", userData.ID).
+		if err := chatroom.Unread(db, userData.ID).
 			Preload("Sender").
 			Preload("Chat").
-			Order("created_at DESC").
+			Order("messages.created_at DESC").
 			Find(&messages).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"success": false,
@@ -637,3 +805,102 @@ func GetUnreadMessages(db *gorm.DB) gin.HandlerFunc {
 	}
 
 }
+
+func findMyChat(c *gin.Context, db *gorm.DB, userID uint) (model.Chat, bool) {
+	chatID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	chat, err := chatroom.Find(db, uint(chatID), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Chat not found",
+		})
+		return chat, false
+	}
+	return chat, true
+}
+
+// populateChats fills the virtual fields of a chat list for the given viewer
+func populateChats(db *gorm.DB, chats []model.Chat, userID uint) {
+	blocked := moderation.BlockedUserIDs(db, userID)
+
+	for i := range chats {
+		// Get last message
+		var lastMsg model.Message
+		if err := db.Where("chat_id = ?", chats[i].ID).
+			Order("created_at DESC").
+			First(&lastMsg).Error; err == nil {
+			chats[i].LastMessage = &lastMsg
+		}
+
+		// Count unread messages
+		var unreadCount int64
+		chatroom.Unread(db, userID).Where("messages.chat_id = ?", chats[i].ID).Count(&unreadCount)
+		chats[i].UnreadCount = int(unreadCount)
+
+		// Set other user
+		chats[i].OtherUser = chatOtherUser(&chats[i], userID)
+		// Presence is withheld in both directions once either side blocks
+		if chats[i].OtherUser != nil && !slices.Contains(blocked, chats[i].OtherUser.ID) {
+			websockets.ApplyPresence(chats[i].OtherUser)
+		}
+	}
+}
+
+// chatOtherUser picks who a chat is shown as: the buyer for the shop's team, the assigned team member
+// or the owner for the buyer, and the other member of a direct chat. Participants must be preloaded.
+func chatOtherUser(chat *model.Chat, userID uint) *model.User {
+	var me *model.ChatParticipant
+	for i := range chat.Participants {
+		if chat.Participants[i].UserID == userID {
+			me = &chat.Participants[i]
+		}
+	}
+
+	var other *model.User
+	for i := range chat.Participants {
+		p := &chat.Participants[i]
+		switch {
+		case p.UserID == userID:
+			continue
+		case chat.ShopID == nil:
+			other = &p.User
+		case me != nil && me.IsShopTeam():
+			if p.Role == model.ChatRoleBuyer {
+				return &p.User
+			}
+		case chat.AssignedTo != nil && p.UserID == *chat.AssignedTo:
+			return &p.User
+		case p.Role == model.ChatRoleOwner:
+			other = &p.User
+		}
+	}
+	return other
+}
+
+// joinChat adds the participants of a new chat: the buyer (User1) plus the shop's owner (User2) and
+// staff for shop chats, both users for direct chats
+func joinChat(tx *gorm.DB, chat model.Chat) error {
+	if chat.ShopID == nil {
+		if err := chatroom.Join(tx, chat.ID, chat.User1ID, model.ChatRoleMember); err != nil {
+			return err
+		}
+		return chatroom.Join(tx, chat.ID, chat.User2ID, model.ChatRoleMember)
+	}
+
+	if err := chatroom.Join(tx, chat.ID, chat.User1ID, model.ChatRoleBuyer); err != nil {
+		return err
+	}
+	if err := chatroom.Join(tx, chat.ID, chat.User2ID, model.ChatRoleOwner); err != nil {
+		return err
+	}
+	for _, userID := range chatroom.Team(tx, *chat.ShopID) {
+		if userID == chat.User2ID {
+			continue
+		}
+		if err := chatroom.Join(tx, chat.ID, userID, model.ChatRoleStaff); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatassist"
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/llmusage"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DraftChatReplies suggests replies for the shop's team to the buyer in a chat. The drafts are only
+// returned; the seller edits and sends one through the normal send endpoint.
+func DraftChatReplies(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var input struct {
+			Count        int    `json:"count" binding:"omitempty,min=1,max=3"`
+			Instructions string `json:"instructions" binding:"max=500"`
+		}
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&input); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": fmt.Sprintf("count must be 1 to %d and instructions at most 500 characters", chatassist.MaxDrafts),
+				})
+				return
+			}
+		}
+
+		chat, ok := findMyChat(c, db, userData.ID)
+		if !ok {
+			return
+		}
+		if chat.ShopID == nil || !chatroom.IsTeam(db, *chat.ShopID, userData.ID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Reply drafts are only available to the shop's team",
+			})
+			return
+		}
+
+		if !allowLLMRequest(c, llmusage.FeatureChatDraft, userData.ID, util.Getenv("CHAT_DRAFT_PER_HOUR", 60)) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(util.Getenv("CHAT_DRAFT_TIMEOUT_S", 30))*time.Second)
+		defer cancel()
+
+		result, err := chatassist.Draft(ctx, db, chatassist.Request{
+			SellerID:     userData.ID,
+			Chat:         chat,
+			BuyerID:      chatroom.Counterpart(db, chat, userData.ID),
+			Count:        input.Count,
+			Instructions: strings.TrimSpace(input.Instructions),
+		})
+		if errors.Is(err, chatassist.ErrNoMessages) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "The chat has no messages to reply to",
+			})
+			return
+		}
+		if err != nil {
+			respondLLMError(c, fmt.Errorf("chat %d: %w", chat.ID, err), "Reply drafting")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    result,
+		})
+	}
+}
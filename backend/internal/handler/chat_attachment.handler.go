@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/attachment"
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/moderation"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// UploadChatAttachment stores an image, PDF or short video for a chat and returns its ID for SendMessage
+func UploadChatAttachment(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		// Verify user is part of the chat
+		chat, ok := findMyChat(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		if moderation.IsChatBlocked(db, chat, userData.ID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "You cannot message this user",
+			})
+			return
+		}
+
+		// Largest allowed kind plus room for the multipart envelope
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, attachment.MaxSize(model.AttachmentVideo)+1<<20)
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Upload a file in the \"file\" form field (max " + strconv.FormatInt(attachment.MaxSize(model.AttachmentVideo)>>20, 10) + " MB)",
+			})
+			return
+		}
+
+		a, err := attachment.Store(db, chat.ID, userData.ID, fh)
+		if err != nil {
+			if errors.Is(err, attachment.ErrInvalid) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": strings.TrimPrefix(err.Error(), attachment.ErrInvalid.Error()+": "),
+				})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to store attachment",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Create("chat_attachment", a.ID).After(a).Success("Chat attachment uploaded"))
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"message": "Attachment uploaded",
+			"data":    a,
+		})
+	}
+}
+
+// DownloadChatAttachment serves an attachment to the chat's participants only
+func DownloadChatAttachment(db *gorm.DB) gin.HandlerFunc {
+	return serveChatAttachment(db, false)
+}
+
+// GetChatAttachmentThumbnail serves the JPEG thumbnail of an image attachment
+func GetChatAttachmentThumbnail(db *gorm.DB) gin.HandlerFunc {
+	return serveChatAttachment(db, true)
+}
+
+func serveChatAttachment(db *gorm.DB, thumbnail bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		attachmentID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+		var a model.ChatAttachment
+		if err := db.Where("id = ? AND chat_id IN (?)", attachmentID, chatroom.Joined(db, userData.ID)).
+			First(&a).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Attachment not found",
+			})
+			return
+		}
+
+		path, contentType := a.StoragePath, a.MimeType
+		if thumbnail {
+			if a.ThumbnailPath == "" {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"message": "Attachment has no thumbnail",
+				})
+				return
+			}
+			path, contentType = a.ThumbnailPath, "image/jpeg"
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Attachment file is missing",
+			})
+			return
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": a.FileName}))
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Cache-Control", "private, max-age=3600")
+		// ServeContent handles Range requests so videos can be seeked
+		http.ServeContent(c.Writer, c.Request, "", stat.ModTime(), f)
+	}
+}
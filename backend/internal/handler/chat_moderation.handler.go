@@ -0,0 +1,474 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/moderation"
+	"github.com/faiz-muttaqin/lgs/backend/internal/websockets"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var reportReasons = []string{
+	model.ReportReasonSpam,
+	model.ReportReasonAbuse,
+	model.ReportReasonScam,
+	model.ReportReasonInappropriate,
+	model.ReportReasonOther,
+}
+
+// Review actions an admin can take on a report
+const (
+	reviewActionNone          = "none"
+	reviewActionRemoveMessage = "remove_message"
+	reviewActionSuspendUser   = "suspend_user"
+)
+
+// GetMyBlocks lists the users the current user has blocked
+func GetMyBlocks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var blocks []model.UserBlock
+		if err := db.Preload("Blocked").Where("blocker_id = ?", userData.ID).
+			Order("created_at DESC").Find(&blocks).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve blocked users",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Blocked users retrieved",
+			"data":    blocks,
+		})
+	}
+}
+
+// BlockUser stops a user from opening chats with, messaging, or seeing the presence of the current user
+func BlockUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		blockedID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+		if uint(blockedID) == userData.ID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Cannot block yourself",
+			})
+			return
+		}
+
+		var target model.User
+		if err := db.Select("id").First(&target, blockedID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "User not found",
+			})
+			return
+		}
+
+		block := model.UserBlock{BlockerID: userData.ID, BlockedID: target.ID}
+		if err := db.Where(block).FirstOrCreate(&block).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to block user",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Create("user_block", block.ID).After(block).Success("User blocked"))
+
+		// The blocked user stops seeing us online straight away
+		websockets.SendEvent(target.ID, websockets.EventPresence, websockets.PresencePayload{UserID: userData.ID})
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "User blocked",
+			"data":    block,
+		})
+	}
+}
+
+// UnblockUser removes a block
+func UnblockUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		blockedID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+		var block model.UserBlock
+		if err := db.Where("blocker_id = ? AND blocked_id = ?", userData.ID, blockedID).First(&block).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "User is not blocked",
+			})
+			return
+		}
+
+		if err := db.Delete(&block).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to unblock user",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Delete("user_block", block.ID).Before(block).Success("User unblocked"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "User unblocked",
+		})
+	}
+}
+
+// ReportChat reports a user, or one of their messages sent to the current user, for admin review.
+// Set block to also block the user.
+func ReportChat(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var input struct {
+			UserID    uint   `json:"user_id"`
+			MessageID *uint  `json:"message_id"`
+			Reason    string `json:"reason" binding:"required"`
+			Details   string `json:"details" binding:"max=2000"`
+			Block     bool   `json:"block"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil || !slices.Contains(reportReasons, input.Reason) ||
+			(input.UserID == 0 && input.MessageID == nil) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Provide user_id or message_id and a reason: " + strings.Join(reportReasons, ", "),
+			})
+			return
+		}
+
+		report := model.ChatReport{
+			ReporterID:     &userData.ID,
+			ReportedUserID: input.UserID,
+			Reason:         input.Reason,
+			Details:        input.Details,
+			Status:         model.ReportOpen,
+		}
+
+		if input.MessageID != nil {
+			// Only messages others sent in the reporter's chats can be reported
+			var message model.Message
+			if err := db.Where("id = ? AND sender_id <> ? AND chat_id IN (?)", *input.MessageID, userData.ID, chatroom.Joined(db, userData.ID)).
+				First(&message).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"message": "Message not found",
+				})
+				return
+			}
+			report.ReportedUserID = message.SenderID
+			report.ChatID = &message.ChatID
+			report.MessageID = &message.ID
+			report.MessageContent = message.Content
+		} else {
+			if input.UserID == userData.ID {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Cannot report yourself",
+				})
+				return
+			}
+			var target model.User
+			if err := db.Select("id").First(&target, input.UserID).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"message": "User not found",
+				})
+				return
+			}
+		}
+
+		// One open report per reporter and target is enough for the queue
+		duplicate := db.Model(&model.ChatReport{}).
+			Where("reporter_id = ? AND reported_user_id = ? AND status = ?", userData.ID, report.ReportedUserID, model.ReportOpen)
+		if report.MessageID != nil {
+			duplicate = duplicate.Where("message_id = ?", *report.MessageID)
+		} else {
+			duplicate = duplicate.Where("message_id IS NULL")
+		}
+		var count int64
+		duplicate.Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "You have already reported this",
+			})
+			return
+		}
+
+		if err := db.Create(&report).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to submit report",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Create("chat_report", report.ID).After(report).Success("Chat report submitted"))
+
+		if input.Block {
+			block := model.UserBlock{BlockerID: userData.ID, BlockedID: report.ReportedUserID}
+			if err := db.Where(block).FirstOrCreate(&block).Error; err == nil {
+				audit.Log(c, db, userData.ID, audit.Create("user_block", block.ID).After(block).Success("User blocked"))
+			}
+		}
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"message": "Report submitted",
+			"data":    report,
+		})
+	}
+}
+
+// GetChatReports lists the moderation queue for admins, oldest open reports first
+func GetChatReports(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireSuperAdmin(c); !ok {
+			return
+		}
+
+		status := c.DefaultQuery("status", model.ReportOpen)
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+
+		query := db.Model(&model.ChatReport{}).Where("status = ?", status)
+		if reason := c.Query("reason"); reason != "" {
+			query = query.Where("reason = ?", reason)
+		}
+		if userID := c.Query("reported_user_id"); userID != "" {
+			query = query.Where("reported_user_id = ?", userID)
+		}
+
+		var total int64
+		query.Count(&total)
+
+		var reports []model.ChatReport
+		if err := query.Preload("Reporter").Preload("ReportedUser").
+			Order("created_at ASC").
+			Offset((page - 1) * limit).Limit(limit).
+			Find(&reports).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve reports",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"reports": reports,
+				"total":   total,
+				"page":    page,
+				"limit":   limit,
+			},
+		})
+	}
+}
+
+// ReviewChatReport closes a report, optionally removing the reported message or suspending the user
+func ReviewChatReport(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUser, ok := requireSuperAdmin(c)
+		if !ok {
+			return
+		}
+
+		reportID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+		var report model.ChatReport
+		if err := db.First(&report, reportID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Report not found",
+			})
+			return
+		}
+
+		var input struct {
+			Status string `json:"status" binding:"required,oneof=dismissed actioned"`
+			Action string `json:"action" binding:"omitempty,oneof=none remove_message suspend_user"`
+			Notes  string `json:"notes" binding:"max=2000"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. status must be dismissed or actioned; action none, remove_message or suspend_user",
+			})
+			return
+		}
+		if input.Action == "" || input.Status == model.ReportDismissed {
+			input.Action = reviewActionNone
+		}
+		if input.Action == reviewActionRemoveMessage && report.MessageID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Report has no message to remove",
+			})
+			return
+		}
+
+		before := report
+		now := time.Now()
+		report.Status = input.Status
+		report.Action = input.Action
+		report.ReviewNotes = input.Notes
+		report.ReviewedBy = &adminUser.ID
+		report.ReviewedAt = &now
+
+		var removed *model.Message
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&report).Error; err != nil {
+				return err
+			}
+			switch input.Action {
+			case reviewActionRemoveMessage:
+				var message model.Message
+				if err := tx.First(&message, *report.MessageID).Error; err != nil {
+					return err
+				}
+				if err := tx.Model(&message).Updates(map[string]any{
+					"is_deleted": true,
+					"content":    "[Message removed by moderator]",
+				}).Error; err != nil {
+					return err
+				}
+				removed = &message
+			case reviewActionSuspendUser:
+				if err := tx.Model(&model.User{}).Where("id = ?", report.ReportedUserID).
+					Update("status", model.StatusSuspended).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to review report",
+			})
+			return
+		}
+
+		audit.Log(c, db, adminUser.ID, audit.Update("chat_report", report.ID).Before(before).After(report).Success("Chat report reviewed"))
+		switch input.Action {
+		case reviewActionRemoveMessage:
+			audit.Log(c, db, adminUser.ID, audit.Update("message", removed.ID).Before(*removed).Success("Message removed by moderator"))
+			websockets.PushMessageDeleted(db, *removed)
+		case reviewActionSuspendUser:
+			audit.Log(c, db, adminUser.ID, audit.Update("user", report.ReportedUserID).Success("User suspended from chat report"))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Report reviewed",
+			"data":    report,
+		})
+	}
+}
+
+// canMessage writes a 403/429 unless the user may send a message in the chat: the sender must not be
+// suspended, no block may stand between the two sides, and the sender must be within the rate limit
+func canMessage(c *gin.Context, db *gorm.DB, user *model.User, chat model.Chat) bool {
+	if user.Status == model.StatusSuspended || user.Status == model.StatusBanned {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Your account cannot send messages",
+		})
+		return false
+	}
+	if moderation.IsChatBlocked(db, chat, user.ID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "You cannot message this user",
+		})
+		return false
+	}
+	if !moderation.AllowMessage(user.ID) {
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"message": "You are sending messages too fast. Please wait a moment",
+		})
+		return false
+	}
+	return true
+}
+
+// screenContent runs the content filter, writing a 422 for rejected text. It returns the verdict so
+// flagged messages can be queued for review once saved.
+func screenContent(c *gin.Context, content string) (moderation.Verdict, bool) {
+	verdict := moderation.Check(content)
+	if verdict.Action == moderation.Reject {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"success": false,
+			"message": "Message was blocked by the content filter (" + strings.Join(verdict.Reasons, ", ") + ")",
+		})
+		return verdict, false
+	}
+	return verdict, true
+}
+
+// flaggedReport is the review queue entry for a message the content filter flagged
+func flaggedReport(message model.Message, verdict moderation.Verdict) model.ChatReport {
+	return model.ChatReport{
+		ReportedUserID: message.SenderID,
+		ChatID:         &message.ChatID,
+		MessageID:      &message.ID,
+		MessageContent: message.Content,
+		Reason:         model.ReportReasonFilter,
+		Details:        "Matched: " + strings.Join(verdict.Reasons, ", "),
+		Status:         model.ReportOpen,
+	}
+}
@@ -0,0 +1,629 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/moderation"
+	"github.com/faiz-muttaqin/lgs/backend/internal/pricing"
+	"github.com/faiz-muttaqin/lgs/backend/internal/websockets"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// errOfferTaken is returned when another request answered the offer first
+var errOfferTaken = errors.New("offer was already answered")
+
+// GetChatOffers lists the offers made in a chat, newest first
+func GetChatOffers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		chat, ok := findMyChat(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var offers []model.ChatOffer
+		if err := db.Where("chat_id = ?", chat.ID).Order("created_at DESC").Find(&offers).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve offers",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Offers retrieved",
+			"data":    offers,
+		})
+	}
+}
+
+// CreateChatOffer lets a buyer propose a price for the seller's product. The product defaults to the
+// one the chat was started from.
+func CreateChatOffer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		chat, ok := findMyChat(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			ProductID *uint   `json:"product_id"`
+			Price     float64 `json:"price" binding:"required,gt=0"`
+			Note      string  `json:"note" binding:"max=500"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. Provide a price greater than 0",
+			})
+			return
+		}
+
+		productID := uint(chat.ProductID.Int64)
+		if input.ProductID != nil {
+			productID = *input.ProductID
+		}
+		if productID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "product_id is required for chats not started from a product",
+			})
+			return
+		}
+
+		var product model.Product
+		if err := db.Preload("Shop").Where("id = ? AND is_active = ?", productID, true).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Product not found",
+			})
+			return
+		}
+
+		// The seller must be in the chat, and the shop's own team can't bargain with it
+		sellerID := product.Shop.UserID
+		if !chatroom.CanAccess(db, chat.ID, sellerID) || chatroom.IsTeam(db, product.ShopID, userData.ID) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Offers can only be made on products sold by the shop you are chatting with",
+			})
+			return
+		}
+		if input.Price >= product.Price {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Offer must be below the listed price",
+			})
+			return
+		}
+		if !canMessage(c, db, userData, chat) {
+			return
+		}
+		verdict, ok := screenContent(c, input.Note)
+		if !ok {
+			return
+		}
+
+		var pending int64
+		db.Model(&model.ChatOffer{}).
+			Where("chat_id = ? AND product_id = ? AND status = ? AND expires_at > ?", chat.ID, product.ID, model.OfferPending, time.Now()).
+			Count(&pending)
+		if pending > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "There is already a pending offer for this product",
+			})
+			return
+		}
+
+		offer := model.ChatOffer{
+			ChatID:     chat.ID,
+			ProductID:  product.ID,
+			BuyerID:    userData.ID,
+			SellerID:   sellerID,
+			ProposedBy: userData.ID,
+			Price:      input.Price,
+			Note:       input.Note,
+		}
+
+		var message model.Message
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if message, err = createOffer(tx, chat, &offer, product); err != nil {
+				return err
+			}
+			return flagOfferNote(tx, &message, offer.Note, verdict)
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to create offer",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Create("chat_offer", offer.ID).After(offer).Success("Chat offer created"))
+
+		websockets.PushChatMessage(db, websockets.EventMessageNew, message)
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"message": "Offer sent",
+			"data":    message,
+		})
+	}
+}
+
+// AcceptChatOffer accepts an offer and issues the buyer a time-limited special price
+func AcceptChatOffer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, offer, ok := loadOfferForResponse(c, db)
+		if !ok {
+			return
+		}
+		before := offer
+
+		var product model.Product
+		db.First(&product, offer.ProductID)
+
+		var special model.SpecialPrice
+		var updated, notice model.Message
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if err = answerOffer(tx, &offer, model.OfferAccepted); err != nil {
+				return err
+			}
+			if special, err = pricing.Issue(tx, offer); err != nil {
+				return err
+			}
+			if updated, err = syncOfferMessage(tx, offer); err != nil {
+				return err
+			}
+			notice, err = postChatMessage(tx, offer.ChatID, userData.ID, model.MessageSystem,
+				fmt.Sprintf("Penawaran %s untuk %s diterima", util.FormatIDR(int(offer.Price)), product.Name),
+				model.SystemPayload{Event: "offer.accepted", OfferID: offer.ID})
+			return err
+		})
+		if !offerAnswered(c, err) {
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Update("chat_offer", offer.ID).Before(before).After(offer).Success("Chat offer accepted"))
+
+		websockets.PushChatMessage(db, websockets.EventMessageEdited, updated)
+		websockets.PushChatMessage(db, websockets.EventMessageNew, notice)
+
+		data := gin.H{"offer": offer}
+		// The token is bound to the buyer; the seller only learns that it was issued
+		if userData.ID == offer.BuyerID {
+			data["special_price"] = special
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Offer accepted",
+			"data":    data,
+		})
+	}
+}
+
+// RejectChatOffer declines an offer
+func RejectChatOffer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, offer, ok := loadOfferForResponse(c, db)
+		if !ok {
+			return
+		}
+		before := offer
+
+		var product model.Product
+		db.First(&product, offer.ProductID)
+
+		var updated, notice model.Message
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if err = answerOffer(tx, &offer, model.OfferRejected); err != nil {
+				return err
+			}
+			if updated, err = syncOfferMessage(tx, offer); err != nil {
+				return err
+			}
+			notice, err = postChatMessage(tx, offer.ChatID, userData.ID, model.MessageSystem,
+				fmt.Sprintf("Penawaran %s untuk %s ditolak", util.FormatIDR(int(offer.Price)), product.Name),
+				model.SystemPayload{Event: "offer.rejected", OfferID: offer.ID})
+			return err
+		})
+		if !offerAnswered(c, err) {
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Update("chat_offer", offer.ID).Before(before).After(offer).Success("Chat offer rejected"))
+
+		websockets.PushChatMessage(db, websockets.EventMessageEdited, updated)
+		websockets.PushChatMessage(db, websockets.EventMessageNew, notice)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Offer rejected",
+			"data":    offer,
+		})
+	}
+}
+
+// CounterChatOffer answers an offer with a different price, which the other side can then accept,
+// reject or counter again
+func CounterChatOffer(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, offer, ok := loadOfferForResponse(c, db)
+		if !ok {
+			return
+		}
+		before := offer
+
+		var input struct {
+			Price float64 `json:"price" binding:"required,gt=0"`
+			Note  string  `json:"note" binding:"max=500"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. Provide a price greater than 0",
+			})
+			return
+		}
+
+		var product model.Product
+		if err := db.First(&product, offer.ProductID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Product not found",
+			})
+			return
+		}
+		if input.Price >= product.Price || input.Price == offer.Price {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Counter offer must differ from the offer and be below the listed price",
+			})
+			return
+		}
+
+		if !canMessage(c, db, userData, offer.Chat) {
+			return
+		}
+		verdict, ok := screenContent(c, input.Note)
+		if !ok {
+			return
+		}
+
+		chat := offer.Chat
+
+		counter := model.ChatOffer{
+			ChatID:      offer.ChatID,
+			ProductID:   offer.ProductID,
+			BuyerID:     offer.BuyerID,
+			SellerID:    offer.SellerID,
+			ProposedBy:  userData.ID,
+			Price:       input.Price,
+			Note:        input.Note,
+			CounterOfID: &offer.ID,
+		}
+
+		var updated, message model.Message
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			if err = answerOffer(tx, &offer, model.OfferCountered); err != nil {
+				return err
+			}
+			if updated, err = syncOfferMessage(tx, offer); err != nil {
+				return err
+			}
+			if message, err = createOffer(tx, chat, &counter, product); err != nil {
+				return err
+			}
+			return flagOfferNote(tx, &message, counter.Note, verdict)
+		})
+		if !offerAnswered(c, err) {
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Update("chat_offer", offer.ID).Before(before).After(offer).Success("Chat offer countered"))
+		audit.Log(c, db, userData.ID, audit.Create("chat_offer", counter.ID).After(counter).Success("Chat counter offer created"))
+
+		websockets.PushChatMessage(db, websockets.EventMessageEdited, updated)
+		websockets.PushChatMessage(db, websockets.EventMessageNew, message)
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"message": "Counter offer sent",
+			"data":    message,
+		})
+	}
+}
+
+// GetMySpecialPrices lists the user's special prices that are still valid
+func GetMySpecialPrices(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var prices []model.SpecialPrice
+		if err := db.Preload("Product").
+			Where("user_id = ? AND expires_at > ?", userData.ID, time.Now()).
+			Order("expires_at ASC").
+			Find(&prices).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve special prices",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Special prices retrieved",
+			"data":    prices,
+		})
+	}
+}
+
+// loadOfferForResponse finds a pending offer the current user is expected to answer, writing the
+// error response itself otherwise. Offers found past their deadline are marked expired.
+func loadOfferForResponse(c *gin.Context, db *gorm.DB) (*model.User, model.ChatOffer, bool) {
+	var offer model.ChatOffer
+
+	userData, err := helper.GetFirebaseUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
+		return nil, offer, false
+	}
+
+	offerID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err := db.Preload("Chat").Where("id = ? AND chat_id IN (?)", offerID, chatroom.Joined(db, userData.ID)).
+		First(&offer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Offer not found",
+		})
+		return nil, offer, false
+	}
+
+	if !canRespond(db, offer, userData.ID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "Only the other participant can respond to this offer",
+		})
+		return nil, offer, false
+	}
+	if moderation.IsChatBlocked(db, offer.Chat, userData.ID) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "You cannot message this user",
+		})
+		return nil, offer, false
+	}
+
+	if offer.IsExpired() {
+		var updated model.Message
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := answerOffer(tx, &offer, model.OfferExpired); err != nil {
+				return err
+			}
+			var err error
+			updated, err = syncOfferMessage(tx, offer)
+			return err
+		})
+		if err != nil && !errors.Is(err, errOfferTaken) {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to update offer",
+			})
+			return nil, offer, false
+		}
+		if err == nil {
+			websockets.PushChatMessage(db, websockets.EventMessageEdited, updated)
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "Offer has expired",
+		})
+		return nil, offer, false
+	}
+
+	if offer.Status != model.OfferPending {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "Offer was already " + offer.Status,
+		})
+		return nil, offer, false
+	}
+
+	return userData, offer, true
+}
+
+// canRespond reports whether the user answers for the side the offer was made to. Offers to the
+// seller can be answered by anyone on the shop's team.
+func canRespond(db *gorm.DB, offer model.ChatOffer, userID uint) bool {
+	if offer.Responder() == userID {
+		return true
+	}
+	if offer.Responder() != offer.SellerID {
+		return false
+	}
+	var chat model.Chat
+	if err := db.First(&chat, offer.ChatID).Error; err != nil || chat.ShopID == nil {
+		return false
+	}
+	return chatroom.IsTeam(db, *chat.ShopID, userID)
+}
+
+// offerAnswered writes the error response for a failed answer transaction
+func offerAnswered(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, errOfferTaken) {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "Offer was already answered",
+		})
+		return false
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"success": false,
+		"message": "Failed to update offer",
+	})
+	return false
+}
+
+// answerOffer moves a pending offer to its final status; the status guard makes concurrent
+// accept/reject/counter requests for the same offer mutually exclusive
+func answerOffer(tx *gorm.DB, offer *model.ChatOffer, status string) error {
+	now := time.Now()
+	res := tx.Model(&model.ChatOffer{}).
+		Where("id = ? AND status = ?", offer.ID, model.OfferPending).
+		Updates(map[string]any{"status": status, "responded_at": now})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errOfferTaken
+	}
+	offer.Status = status
+	offer.RespondedAt = &now
+	return nil
+}
+
+// createOffer stores a new pending offer together with the chat message that carries it
+func createOffer(tx *gorm.DB, chat model.Chat, offer *model.ChatOffer, product model.Product) (model.Message, error) {
+	offer.Status = model.OfferPending
+	offer.ExpiresAt = time.Now().Add(time.Duration(util.Getenv("CHAT_OFFER_TTL_H", 48)) * time.Hour)
+	if err := tx.Create(offer).Error; err != nil {
+		return model.Message{}, err
+	}
+
+	content := fmt.Sprintf("Menawar %s untuk %s", util.FormatIDR(int(offer.Price)), product.Name)
+	if offer.CounterOfID != nil {
+		content = fmt.Sprintf("Tawaran balik %s untuk %s", util.FormatIDR(int(offer.Price)), product.Name)
+	}
+	message, err := postChatMessage(tx, chat.ID, offer.ProposedBy, model.MessageOffer, content, offerPayload(*offer, product))
+	if err != nil {
+		return message, err
+	}
+
+	offer.MessageID = message.ID
+	return message, tx.Model(offer).Update("message_id", message.ID).Error
+}
+
+// flagOfferNote queues an offer for review when its note was flagged by the content filter
+func flagOfferNote(tx *gorm.DB, message *model.Message, note string, verdict moderation.Verdict) error {
+	if verdict.Action != moderation.Flag {
+		return nil
+	}
+	message.IsFlagged = true
+	if err := tx.Model(message).Update("is_flagged", true).Error; err != nil {
+		return err
+	}
+	report := flaggedReport(*message, verdict)
+	report.MessageContent = note
+	return tx.Create(&report).Error
+}
+
+// syncOfferMessage copies the offer's status into the payload of the message that carries it
+func syncOfferMessage(tx *gorm.DB, offer model.ChatOffer) (model.Message, error) {
+	var message model.Message
+	if err := tx.First(&message, offer.MessageID).Error; err != nil {
+		return message, err
+	}
+
+	var payload model.OfferPayload
+	if err := json.Unmarshal(message.Payload, &payload); err != nil {
+		return message, err
+	}
+	payload.Status = offer.Status
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return message, err
+	}
+	message.Payload = raw
+	return message, tx.Model(&message).Update("payload", message.Payload).Error
+}
+
+// postChatMessage adds a structured message to a chat from one of its participants
+func postChatMessage(tx *gorm.DB, chatID, senderID uint, kind, content string, payload any) (model.Message, error) {
+	var chat model.Chat
+	if err := tx.First(&chat, chatID).Error; err != nil {
+		return model.Message{}, err
+	}
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return model.Message{}, err
+	}
+	message := model.Message{
+		ChatID:     chat.ID,
+		SenderID:   senderID,
+		ReceiverID: chatroom.Counterpart(tx, chat, senderID),
+		Kind:       kind,
+		Content:    content,
+		Payload:    raw,
+	}
+	if err := tx.Create(&message).Error; err != nil {
+		return message, err
+	}
+	if err := tx.Model(&chat).Update("updated_at", time.Now()).Error; err != nil {
+		return message, err
+	}
+	return message, events.Publish(tx, events.MessageSent{Message: message})
+}
+
+func offerPayload(offer model.ChatOffer, product model.Product) model.OfferPayload {
+	return model.OfferPayload{
+		OfferID:    offer.ID,
+		Product:    product.ProductCard(),
+		Price:      offer.Price,
+		Note:       offer.Note,
+		Status:     offer.Status,
+		ProposedBy: offer.ProposedBy,
+		CounterOf:  offer.CounterOfID,
+		ExpiresAt:  offer.ExpiresAt,
+	}
+}
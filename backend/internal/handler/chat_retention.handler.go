@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/retention"
+	"github.com/faiz-muttaqin/lgs/backend/internal/transcript"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ExportChat downloads a chat's full transcript for dispute handling (?format=json|txt|pdf)
+func ExportChat(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUser, ok := requireSuperAdmin(c)
+		if !ok {
+			return
+		}
+
+		format := c.DefaultQuery("format", transcript.FormatJSON)
+		if !slices.Contains(transcript.Formats, format) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "format must be one of " + strings.Join(transcript.Formats, ", "),
+			})
+			return
+		}
+
+		chatID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+		t, err := transcript.Load(db.Unscoped(), uint(chatID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Chat not found",
+			})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to export chat",
+			})
+			return
+		}
+
+		var body []byte
+		contentType := "application/json"
+		switch format {
+		case transcript.FormatJSON:
+			body, _ = json.MarshalIndent(t, "", "  ")
+		case transcript.FormatText:
+			body, contentType = []byte(t.Text()), "text/plain; charset=utf-8"
+		case transcript.FormatPDF:
+			body, contentType = t.PDF(), "application/pdf"
+		}
+
+		audit.Log(c, db, adminUser.ID, audit.Export("chat", t.ChatID).
+			After(gin.H{"format": format, "messages": len(t.Messages)}).
+			Success("Chat transcript exported"))
+
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="chat-%d-transcript.%s"`, t.ChatID, format))
+		c.Header("Cache-Control", "no-store")
+		c.Data(http.StatusOK, contentType, body)
+	}
+}
+
+// SetChatLegalHold places a chat under legal hold, exempting it from the retention policy, or lifts it
+func SetChatLegalHold(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUser, ok := requireSuperAdmin(c)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Hold   bool   `json:"hold"`
+			Reason string `json:"reason" binding:"max=500"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil || (input.Hold && strings.TrimSpace(input.Reason) == "") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Provide hold, and a reason when placing a hold",
+			})
+			return
+		}
+
+		chatID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+		var chat model.Chat
+		if err := db.Unscoped().First(&chat, chatID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Chat not found",
+			})
+			return
+		}
+		before := chat
+
+		updates := map[string]any{"legal_hold_at": nil, "legal_hold_reason": ""}
+		message := "Legal hold lifted"
+		if input.Hold {
+			updates = map[string]any{"legal_hold_at": time.Now(), "legal_hold_reason": strings.TrimSpace(input.Reason)}
+			message = "Legal hold placed"
+		}
+		if err := db.Unscoped().Model(&chat).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to update legal hold",
+			})
+			return
+		}
+		db.Unscoped().First(&chat, chat.ID)
+
+		audit.Log(c, db, adminUser.ID, audit.Update("chat", chat.ID).Before(before).After(chat).Success(message))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": message,
+			"data":    chat,
+		})
+	}
+}
+
+// GetChatRetention shows the retention policy and the chats exempt from it
+func GetChatRetention(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireSuperAdmin(c); !ok {
+			return
+		}
+
+		var held []model.Chat
+		if err := db.Unscoped().
+			Select("id", "user1_id", "user2_id", "shop_id", "legal_hold_at", "legal_hold_reason", "created_at").
+			Where("legal_hold_at IS NOT NULL").
+			Order("legal_hold_at DESC").
+			Find(&held).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve legal holds",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"policy":      retention.CurrentPolicy(),
+				"legal_holds": held,
+			},
+		})
+	}
+}
+
+// RunChatRetention applies the retention policy now instead of waiting for the next scheduled run
+func RunChatRetention(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUser, ok := requireSuperAdmin(c)
+		if !ok {
+			return
+		}
+
+		result, err := retention.Run(db)
+		switch {
+		case errors.Is(err, retention.ErrDisabled):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Chat retention is disabled. Set CHAT_RETENTION_DAYS to enable it",
+			})
+			return
+		case errors.Is(err, retention.ErrRunning):
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "Chat retention is already running",
+			})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Chat retention failed",
+			})
+			return
+		}
+
+		audit.Log(c, db, adminUser.ID, audit.Update("chat_retention", "run").After(result).Success("Chat retention run"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Chat retention applied",
+			"data":    result,
+		})
+	}
+}
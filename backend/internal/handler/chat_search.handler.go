@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultMessageLimit = 50
+	maxMessageLimit     = 100
+	minSearchLength     = 2
+)
+
+// SearchChatMessages searches the text of one chat's messages, newest first. Results page with before_id.
+func SearchChatMessages(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		chat, ok := findMyChat(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		pattern, ok := searchPattern(c)
+		if !ok {
+			return
+		}
+
+		query := db.Where("messages.chat_id = ? AND messages.is_deleted = false", chat.ID).
+			Where("LOWER(messages.content) LIKE ? ESCAPE '!'", pattern)
+		searchMessages(c, query)
+	}
+}
+
+// SearchMessages searches across all of the caller's chats. Each result carries its chat_id, so the
+// client can open it with GET /chats/:id/messages?around_id=<id>.
+func SearchMessages(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		pattern, ok := searchPattern(c)
+		if !ok {
+			return
+		}
+
+		query := db.Joins("JOIN chats ON chats.id = messages.chat_id AND chats.deleted_at IS NULL").
+			Where("chats.id IN (?)", chatroom.Joined(db, userData.ID)).
+			Where("messages.is_deleted = false").
+			Where("LOWER(messages.content) LIKE ? ESCAPE '!'", pattern)
+		searchMessages(c, query)
+	}
+}
+
+func searchMessages(c *gin.Context, query *gorm.DB) {
+	limit := messageLimit(c)
+	beforeID, _ := strconv.ParseUint(c.Query("before_id"), 10, 32)
+
+	messages, hasOlder, err := messagesBefore(query, uint(beforeID), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to search messages",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    messagePage(messages, limit, hasOlder, beforeID > 0),
+	})
+}
+
+// searchPattern reads ?q= and turns it into a case-insensitive LIKE pattern, escaping wildcards
+func searchPattern(c *gin.Context) (string, bool) {
+	q := strings.TrimSpace(c.Query("q"))
+	if len([]rune(q)) < minSearchLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "Search query must be at least " + strconv.Itoa(minSearchLength) + " characters",
+		})
+		return "", false
+	}
+	q = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(strings.ToLower(q))
+	return "%" + q + "%", true
+}
+
+func messageLimit(c *gin.Context) int {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultMessageLimit)))
+	if err != nil || limit < 1 {
+		return defaultMessageLimit
+	}
+	return min(limit, maxMessageLimit)
+}
+
+// messagesBefore returns up to limit messages with an ID below beforeID (0 means the latest),
+// newest first, and whether older ones remain
+func messagesBefore(query *gorm.DB, beforeID uint, limit int) ([]model.Message, bool, error) {
+	if beforeID > 0 {
+		query = query.Where("messages.id < ?", beforeID)
+	}
+	var messages []model.Message
+	if err := withMessageRelations(query).Order("messages.id DESC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		return nil, false, err
+	}
+	if len(messages) > limit {
+		return messages[:limit], true, nil
+	}
+	return messages, false, nil
+}
+
+// messagesAfter returns up to limit messages directly after afterID, newest first, and whether
+// newer ones remain
+func messagesAfter(query *gorm.DB, afterID uint, limit int) ([]model.Message, bool, error) {
+	var messages []model.Message
+	if limit < 1 {
+		return messages, true, nil
+	}
+	if err := withMessageRelations(query.Where("messages.id > ?", afterID)).
+		Order("messages.id ASC").Limit(limit + 1).Find(&messages).Error; err != nil {
+		return nil, false, err
+	}
+	hasNewer := len(messages) > limit
+	if hasNewer {
+		messages = messages[:limit]
+	}
+	slices.Reverse(messages)
+	return messages, hasNewer, nil
+}
+
+func withMessageRelations(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Sender").
+		Preload("Receiver").
+		Preload("ReplyToMessage").
+		Preload("ReplyToMessage.Sender").
+		Preload("Attachment")
+}
+
+// messagePage builds the response for a page of messages ordered newest first. The before_id and
+// after_id cursors fetch the next older and newer pages.
+func messagePage(messages []model.Message, limit int, hasOlder, hasNewer bool) gin.H {
+	if messages == nil {
+		messages = []model.Message{}
+	}
+	page := gin.H{
+		"messages":  messages,
+		"limit":     limit,
+		"has_older": hasOlder,
+		"has_newer": hasNewer,
+	}
+	if len(messages) > 0 {
+		page["before_id"] = messages[len(messages)-1].ID
+		page["after_id"] = messages[0].ID
+	}
+	return page
+}
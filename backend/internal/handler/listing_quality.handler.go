@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/llmusage"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/productassist"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetProductQuality grades a saved product for its seller, asking the model for the category when the
+// classifier is unsure. It follows up on the quick grade returned when the product is created.
+func GetProductQuality(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var product model.Product
+		var shop model.Shop
+		if err := db.First(&product, c.Param("id")).Error; err != nil ||
+			(userData.RoleID != 1 && (db.Where("user_id = ?", userData.ID).First(&shop).Error != nil || product.ShopID != shop.ID)) {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Product not found",
+			})
+			return
+		}
+
+		if !allowLLMRequest(c, llmusage.FeatureListing, userData.ID, util.Getenv("PRODUCT_QUALITY_PER_HOUR", 60)) {
+			return
+		}
+
+		var images int64
+		db.Model(&model.ProductImage{}).Where("product_id = ?", product.ID).Count(&images)
+		if product.ImageURL != "" {
+			images++
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(util.Getenv("PRODUCT_QUALITY_TIMEOUT_S", 10))*time.Second)
+		defer cancel()
+
+		quality, err := productassist.ReviewListing(ctx, db, userData.ID, product, int(images))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to grade the product",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Product graded",
+			"data":    quality,
+		})
+	}
+}
+
+// GetListingQuality reports listing quality per shop, weakest shops first (?page=&limit=)
+func GetListingQuality(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireSuperAdmin(c); !ok {
+			return
+		}
+		page, limit := listingQualityPage(c)
+
+		report, err := productassist.ShopReport(db)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to build listing quality report",
+			})
+			return
+		}
+
+		total := len(report)
+		start := min((page-1)*limit, total)
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"shops": report[start:min(start+limit, total)],
+				"total": total,
+				"page":  page,
+				"limit": limit,
+			},
+		})
+	}
+}
+
+// GetShopListingQuality grades the listings of one shop, weakest first (?issue=&page=&limit=)
+func GetShopListingQuality(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireSuperAdmin(c); !ok {
+			return
+		}
+		page, limit := listingQualityPage(c)
+
+		shopID, err := strconv.ParseUint(c.Param("shop_id"), 10, 64)
+		var shop model.Shop
+		if err != nil || db.Select("id", "name").First(&shop, shopID).Error != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Shop not found",
+			})
+			return
+		}
+		issue := c.Query("issue")
+		if issue != "" && !slices.Contains(productassist.Issues, issue) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "issue must be one of " + strings.Join(productassist.Issues, ", "),
+			})
+			return
+		}
+
+		listings, err := productassist.ShopListings(db, shop.ID, issue)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to grade listings",
+			})
+			return
+		}
+
+		total := len(listings)
+		start := min((page-1)*limit, total)
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"shop_id":   shop.ID,
+				"shop_name": shop.Name,
+				"listings":  listings[start:min(start+limit, total)],
+				"total":     total,
+				"page":      page,
+				"limit":     limit,
+			},
+		})
+	}
+}
+
+func listingQualityPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/llmusage"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/kvstore"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/llm"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// GetLLMUsage reports language model usage per feature, per day and for the heaviest users
+// (?from=YYYY-MM-DD&to=YYYY-MM-DD, default the last 30 days; ?feature=)
+func GetLLMUsage(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireSuperAdmin(c); !ok {
+			return
+		}
+
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		from, to := today.AddDate(0, 0, -29), today
+		var err error
+		if v := c.Query("from"); v != "" {
+			if from, err = time.ParseInLocation("2006-01-02", v, now.Location()); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Invalid from date (use YYYY-MM-DD)",
+				})
+				return
+			}
+		}
+		if v := c.Query("to"); v != "" {
+			if to, err = time.ParseInLocation("2006-01-02", v, now.Location()); err != nil || to.Before(from) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Invalid to date (use YYYY-MM-DD, not before from)",
+				})
+				return
+			}
+		}
+
+		report, err := llmusage.Summarize(db, from, to.AddDate(0, 0, 1), c.Query("feature"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to build LLM usage report",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    report,
+		})
+	}
+}
+
+// allowLLMRequest counts a request against the user's hourly limit for a feature (0 is unlimited),
+// writing the 429 response itself when it is exceeded
+func allowLLMRequest(c *gin.Context, feature string, userID uint, perHour int) bool {
+	return allowLLMRequestFrom(c, feature, fmt.Sprint(userID), perHour)
+}
+
+// allowLLMRequestFrom is allowLLMRequest for any caller key, e.g. the client IP of public endpoints
+func allowLLMRequestFrom(c *gin.Context, feature, caller string, perHour int) bool {
+	if perHour <= 0 {
+		return true
+	}
+	window := time.Now().Unix() / 3600
+	n, err := kvstore.IncrKey(fmt.Sprintf("llm:rate:%s:%s:%d", feature, caller, window), time.Hour)
+	if err != nil || n <= int64(perHour) {
+		return true
+	}
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"success": false,
+		"message": "Too many requests, try again later",
+	})
+	return false
+}
+
+// respondLLMError answers a failed LLM feature; what names it in messages, e.g. "Reply drafting"
+func respondLLMError(c *gin.Context, err error, what string) {
+	switch {
+	case errors.Is(err, llm.ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": what + " is not available",
+		})
+	case errors.Is(err, llmusage.ErrBudgetExceeded):
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"success": false,
+			"message": what + " has reached its daily limit, try again tomorrow",
+		})
+	default:
+		logrus.Errorf("%s failed: %v", what, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"success": false,
+			"message": what + " failed, try again",
+		})
+	}
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/notification"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetMyNotifications lists the user's notifications, newest first
+func GetMyNotifications(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+
+		query := db.Model(&model.Notification{}).Where("user_id = ?", userData.ID)
+		if c.Query("unread") == "true" {
+			query = query.Where("read_at IS NULL")
+		}
+		if notificationType := c.Query("type"); notificationType != "" {
+			query = query.Where("type = ?", notificationType)
+		}
+
+		var total int64
+		query.Count(&total)
+
+		var notifications []model.Notification
+		if err := query.Order("created_at DESC").
+			Limit(limit).
+			Offset((page - 1) * limit).
+			Find(&notifications).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve notifications",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"notifications": notifications,
+				"total":         total,
+				"unread_count":  notification.UnreadCount(db, userData.ID),
+				"page":          page,
+				"limit":         limit,
+			},
+		})
+	}
+}
+
+// GetUnreadNotificationCount gets the user's unread notification count
+func GetUnreadNotificationCount(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"unread_count": notification.UnreadCount(db, userData.ID),
+			},
+		})
+	}
+}
+
+// MarkNotificationRead marks a single notification as read
+func MarkNotificationRead(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		notificationID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+		var n model.Notification
+		if err := db.Where("id = ? AND user_id = ?", notificationID, userData.ID).First(&n).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Notification not found",
+			})
+			return
+		}
+
+		if n.ReadAt == nil {
+			now := time.Now()
+			n.ReadAt = &now
+			db.Model(&n).Update("read_at", now)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Notification marked as read",
+			"data": gin.H{
+				"unread_count": notification.UnreadCount(db, userData.ID),
+			},
+		})
+	}
+}
+
+// MarkAllNotificationsRead marks all of the user's notifications as read
+func MarkAllNotificationsRead(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		res := db.Model(&model.Notification{}).
+			Where("user_id = ? AND read_at IS NULL", userData.ID).
+			Update("read_at", time.Now())
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "All notifications marked as read",
+			"data": gin.H{
+				"updated": res.RowsAffected,
+			},
+		})
+	}
+}
+
+// GetNotificationPreferences returns the user's channel preferences for every notification type
+func GetNotificationPreferences(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var saved []model.NotificationPreference
+		db.Where("user_id = ?", userData.ID).Find(&saved)
+
+		prefs := make([]model.NotificationPreference, 0, len(model.NotificationTypes))
+		for _, t := range model.NotificationTypes {
+			pref := model.DefaultNotificationPreference(userData.ID, t)
+			for _, s := range saved {
+				if s.Type == t {
+					pref = s
+					break
+				}
+			}
+			prefs = append(prefs, pref)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    prefs,
+		})
+	}
+}
+
+// UpdateNotificationPreferences upserts the user's channel preferences per notification type
+func UpdateNotificationPreferences(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var input []struct {
+			Type  string `json:"type" binding:"required"`
+			InApp bool   `json:"in_app"`
+			Email bool   `json:"email"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. Expected a list of {type, in_app, email}",
+			})
+			return
+		}
+
+		prefs := make([]model.NotificationPreference, 0, len(input))
+		for _, in := range input {
+			if !slices.Contains(model.NotificationTypes, in.Type) {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Unknown notification type: " + in.Type,
+				})
+				return
+			}
+			prefs = append(prefs, model.NotificationPreference{
+				UserID: userData.ID,
+				Type:   in.Type,
+				InApp:  in.InApp,
+				Email:  in.Email,
+			})
+		}
+
+		if len(prefs) > 0 {
+			if err := db.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+				DoUpdates: clause.AssignmentColumns([]string{"in_app", "email", "updated_at"}),
+			}).Create(&prefs).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"message": "Failed to save preferences",
+				})
+				return
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Preferences saved",
+			"data":    prefs,
+		})
+	}
+}
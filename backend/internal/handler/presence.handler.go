@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/websockets"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetPresence returns online status and last seen time for ?user_ids=1,2,3.
+// Only users the caller shares a chat with are included.
+func GetPresence(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var requested []uint
+		for _, part := range strings.Split(c.Query("user_ids"), ",") {
+			if id, err := strconv.ParseUint(strings.TrimSpace(part), 10, 32); err == nil {
+				requested = append(requested, uint(id))
+			}
+		}
+		if len(requested) == 0 || len(requested) > 100 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "user_ids must list between 1 and 100 user IDs",
+			})
+			return
+		}
+
+		partners := websockets.ChatPartners(db, userData.ID)
+		allowed := slices.DeleteFunc(requested, func(id uint) bool {
+			return !slices.Contains(partners, id)
+		})
+
+		var users []model.User
+		if len(allowed) > 0 {
+			if err := db.Select("id", "last_seen_at", "hide_online_status").
+				Where("id IN ?", allowed).
+				Find(&users).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"message": "Failed to retrieve presence",
+				})
+				return
+			}
+		}
+
+		presence := make([]websockets.PresencePayload, 0, len(users))
+		for i := range users {
+			websockets.ApplyPresence(&users[i])
+			presence = append(presence, websockets.PresencePayload{
+				UserID:     users[i].ID,
+				Online:     *users[i].Online,
+				LastSeenAt: users[i].LastSeen,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"presence": presence,
+			},
+		})
+	}
+}
+
+// GetPresenceSettings returns the caller's presence privacy setting
+func GetPresenceSettings(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"hide_online_status": userData.HideOnlineStatus,
+			},
+		})
+	}
+}
+
+// UpdatePresenceSettings lets a user hide their online status and last seen time from chat partners
+func UpdatePresenceSettings(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var input struct {
+			HideOnlineStatus *bool `json:"hide_online_status" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. Required fields: hide_online_status",
+			})
+			return
+		}
+
+		old := gin.H{"hide_online_status": userData.HideOnlineStatus}
+		if err := db.Model(&model.User{}).Where("id = ?", userData.ID).
+			Update("hide_online_status", *input.HideOnlineStatus).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to update presence settings",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Update("user", userData.ID).Before(old).After(input).Success("Presence settings updated"))
+		go websockets.AnnouncePresence(db, userData.ID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Presence settings updated",
+			"data": gin.H{
+				"hide_online_status": *input.HideOnlineStatus,
+			},
+		})
+	}
+}
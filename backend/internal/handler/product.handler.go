@@ -6,8 +6,11 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
 	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
 	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/pricing"
+	"github.com/faiz-muttaqin/lgs/backend/internal/productassist"
 	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -116,6 +119,33 @@ func GetProductByID(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		// A special price token from an accepted chat offer is only honored for the buyer it was issued to
+		if token := c.Query("price_token"); token != "" {
+			userData, err := helper.GetFirebaseUser(c)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"success": false,
+					"message": "Unauthorized",
+				})
+				return
+			}
+			quote, err := pricing.ForUser(db, product, userData.ID, token)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Special price is invalid or has expired",
+				})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"message": "Product fetched successfully",
+				"data":    product,
+				"pricing": quote,
+			})
+			return
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"message": "Product fetched successfully",
@@ -173,7 +203,12 @@ func CreateProduct(db *gorm.DB) gin.HandlerFunc {
 			product.Status = model.ProductStatusDraft
 		}
 
-		if err := db.Create(&product).Error; err != nil {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&product).Error; err != nil {
+				return err
+			}
+			return events.Publish(tx, events.ProductCreated{Product: product})
+		}); err != nil {
 			// Log failed creation
 			audit.Log(c, db, userData.ID,
 				audit.Create("product", product.ID).
@@ -198,10 +233,17 @@ func CreateProduct(db *gorm.DB) gin.HandlerFunc {
 				Success("Product created successfully"),
 		)
 
+		// Advisory listing check from the rules and the classifier; the model's category suggestion is
+		// left to GET /products/:id/quality
+		images := len(product.Images)
+		if product.ImageURL != "" {
+			images++
+		}
 		c.JSON(http.StatusCreated, gin.H{
 			"success": true,
 			"message": "Product created successfully",
 			"data":    product,
+			"quality": productassist.CheckListing(db, product, images),
 		})
 	}
 }
@@ -273,8 +315,17 @@ func UpdateProduct(db *gorm.DB) gin.HandlerFunc {
 		// Store old product data for audit
 		oldProduct := product
 
-		// Update product
-		if err := db.Model(&product).Updates(updateData).Error; err != nil {
+		// Update product and publish the change in the same transaction
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&product).Updates(updateData).Error; err != nil {
+				return err
+			}
+			var updated model.Product
+			if err := tx.First(&updated, product.ID).Error; err != nil {
+				return err
+			}
+			return events.Publish(tx, events.ProductUpdated{Before: oldProduct, After: updated})
+		}); err != nil {
 			// Log failed update
 			audit.Log(
 				c,
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/llmusage"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/productassist"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// SuggestProductCopy drafts a description, subtitle and SEO title/description for the product editor.
+// It works from the editor's unsaved fields, optionally completed from a saved product (product_id).
+// Nothing is saved; the seller applies the suggestion through the normal product update.
+// With estimate_only the model is not called and only the token estimate is returned.
+func SuggestProductCopy(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var input struct {
+			ProductID     uint                      `json:"product_id"`
+			Name          string                    `json:"name" binding:"max=255"`
+			CategoryID    uint                      `json:"category_id"`
+			SubCategoryID uint                      `json:"sub_category_id"`
+			Attributes    []productassist.Attribute `json:"attributes" binding:"max=30,dive"`
+			ImageAlts     []string                  `json:"image_alts" binding:"max=10,dive,max=200"`
+			Description   string                    `json:"description" binding:"max=5000"` // Current text to improve on
+			Language      string                    `json:"language"`
+			EstimateOnly  bool                      `json:"estimate_only"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid request body",
+				"error":   err.Error(),
+			})
+			return
+		}
+		if input.Language == "" {
+			input.Language = productassist.LanguageIndonesian
+		}
+		if !slices.Contains(productassist.Languages, input.Language) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "language must be one of " + strings.Join(productassist.Languages, ", "),
+			})
+			return
+		}
+
+		var shop model.Shop
+		if err := db.Where("user_id = ?", userData.ID).First(&shop).Error; err != nil && userData.RoleID != 1 {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "You must create a shop first",
+			})
+			return
+		}
+
+		copyInput := productassist.CopyInput{
+			Name:       strings.TrimSpace(input.Name),
+			Attributes: input.Attributes,
+			ImageAlts:  input.ImageAlts,
+			Current:    input.Description,
+			Language:   input.Language,
+		}
+		if input.ProductID != 0 {
+			var product model.Product
+			if err := db.Preload("Variants").First(&product, input.ProductID).Error; err != nil ||
+				(userData.RoleID != 1 && product.ShopID != shop.ID) {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"message": "Product not found",
+				})
+				return
+			}
+			// The editor's values win; the saved product fills what was left empty
+			if copyInput.Name == "" {
+				copyInput.Name = product.Name
+			}
+			if copyInput.Current == "" {
+				copyInput.Current = product.Description
+			}
+			if input.CategoryID == 0 {
+				input.CategoryID, input.SubCategoryID = product.CategoryID, product.SubCategoryID
+			}
+			if len(input.Attributes) == 0 {
+				copyInput.Attributes = productAttributes(product)
+			}
+		}
+		if copyInput.Name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Product name is required",
+			})
+			return
+		}
+		if input.CategoryID != 0 {
+			var category model.Category
+			if db.Select("name").First(&category, input.CategoryID).Error == nil {
+				copyInput.Category = category.Name
+			}
+		}
+		if input.SubCategoryID != 0 {
+			var sub model.SubCategory
+			if db.Select("name").First(&sub, input.SubCategoryID).Error == nil {
+				copyInput.SubCategory = sub.Name
+			}
+		}
+
+		estimate := productassist.EstimateCopy(copyInput)
+		limits := gin.H{
+			"subtitle":        productassist.MaxSubtitle,
+			"description":     productassist.MaxDescription,
+			"seo_title":       productassist.MaxSEOTitle,
+			"seo_description": productassist.MaxSEODescription,
+		}
+		if input.EstimateOnly {
+			c.JSON(http.StatusOK, gin.H{
+				"success": true,
+				"data": gin.H{
+					"estimate": estimate,
+					"limits":   limits,
+				},
+			})
+			return
+		}
+
+		if !allowLLMRequest(c, llmusage.FeatureProductCopy, userData.ID, util.Getenv("PRODUCT_COPY_PER_HOUR", 30)) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(util.Getenv("PRODUCT_COPY_TIMEOUT_S", 60))*time.Second)
+		defer cancel()
+
+		suggestion, cost, err := productassist.SuggestCopy(ctx, db, userData.ID, copyInput)
+		if errors.Is(err, productassist.ErrBadOutput) {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"success": false,
+				"message": "The suggestion could not be generated, try again",
+			})
+			return
+		}
+		if err != nil {
+			respondLLMError(c, err, "Product copy suggestions")
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Suggestion generated; review it before saving",
+			"data": gin.H{
+				"suggestion": suggestion,
+				"language":   input.Language,
+				"cost":       cost,
+				"estimate":   estimate,
+				"limits":     limits,
+			},
+		})
+	}
+}
+
+// productAttributes lists what a saved product already tells about itself
+func productAttributes(product model.Product) []productassist.Attribute {
+	var attrs []productassist.Attribute
+	if product.Weight > 0 {
+		attrs = append(attrs, productassist.Attribute{Name: "Weight", Value: fmt.Sprintf("%d g", product.Weight)})
+	}
+	var variants []string
+	for _, v := range product.Variants {
+		variants = append(variants, v.Name)
+	}
+	if len(variants) > 0 {
+		attrs = append(attrs, productassist.Attribute{Name: "Variants", Value: strings.Join(variants, ", ")})
+	}
+	return attrs
+}
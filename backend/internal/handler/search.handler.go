@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/llmusage"
+	"github.com/faiz-muttaqin/lgs/backend/internal/semantic"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/llm"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SemanticSearch - Public endpoint to find products by meaning (?q=, ?mode=semantic|hybrid, ?weight=, ?page=, ?limit=)
+func SemanticSearch(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := strings.TrimSpace(c.Query("q"))
+		if len([]rune(q)) < 2 || len([]rune(q)) > 200 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Search query must be 2 to 200 characters",
+			})
+			return
+		}
+		mode := c.DefaultQuery("mode", semantic.ModeSemantic)
+		if !slices.Contains(semantic.Modes, mode) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "mode must be one of " + strings.Join(semantic.Modes, ", "),
+			})
+			return
+		}
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		page, limit = max(page, 1), min(max(limit, 1), 100)
+		weight, _ := strconv.ParseFloat(c.Query("weight"), 64)
+
+		// Uncached queries are embedded by the paid provider; anonymous callers are limited per IP
+		if !allowLLMRequestFrom(c, llmusage.FeatureSearch, "ip:"+c.ClientIP(), util.Getenv("SEARCH_PER_HOUR", 120)) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(util.Getenv("SEARCH_TIMEOUT_S", 10))*time.Second)
+		defer cancel()
+
+		result, err := semantic.Search(ctx, db, semantic.Query{
+			Text:   q,
+			Mode:   mode,
+			Weight: weight,
+			Limit:  limit,
+			Offset: (page - 1) * limit,
+		})
+		if errors.Is(err, llm.ErrNotConfigured) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"success": false,
+				"message": "Semantic search is not available, use mode=hybrid or the regular product search",
+			})
+			return
+		}
+		if errors.Is(err, llmusage.ErrBudgetExceeded) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"success": false,
+				"message": "Semantic search has reached its daily limit, use mode=hybrid or the regular product search",
+			})
+			return
+		}
+		if err != nil {
+			logrus.Errorf("Semantic search for %q failed: %v", q, err)
+			c.JSON(http.StatusBadGateway, gin.H{
+				"success": false,
+				"message": "Search failed, try again",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"results":  result.Results,
+				"total":    result.Total,
+				"mode":     result.Mode,
+				"degraded": result.Degraded,
+				"page":     page,
+				"limit":    limit,
+			},
+		})
+	}
+}
+
+// GetSearchIndex shows how much of the catalog is embedded for semantic search
+func GetSearchIndex(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := requireSuperAdmin(c); !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    semantic.CurrentStatus(db),
+		})
+	}
+}
+
+// RebuildSearchIndex re-embeds every product in the background
+func RebuildSearchIndex(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminUser, ok := requireSuperAdmin(c)
+		if !ok {
+			return
+		}
+		if llm.Default() == nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"success": false,
+				"message": "No LLM provider is configured",
+			})
+			return
+		}
+		if err := semantic.RebuildAll(db); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to start the rebuild",
+			})
+			return
+		}
+
+		audit.Log(c, db, adminUser.ID, audit.Update("search_index", "rebuild").Success("Semantic search index rebuild started"))
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": "Rebuild started",
+			"data":    semantic.CurrentStatus(db),
+		})
+	}
+}
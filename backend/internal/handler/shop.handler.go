@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
 	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
 	"github.com/faiz-muttaqin/lgs/backend/internal/model"
 	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
@@ -110,7 +111,12 @@ func CreateMyShop(db *gorm.DB) gin.HandlerFunc {
 			IsActive:    true,
 		}
 
-		if err := db.Create(&shop).Error; err != nil {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&shop).Error; err != nil {
+				return err
+			}
+			return events.Publish(tx, events.ShopCreated{Shop: shop})
+		}); err != nil {
 			// Log failed creation
 			audit.Log(c, db, userData.ID,
 				audit.Create("shop", shop.ID).After(shop).Failed(err),
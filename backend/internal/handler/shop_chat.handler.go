@@ -0,0 +1,489 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/autoreply"
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/websockets"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const maxQuickReplies = 50
+
+// GetMyChatSettings returns the shop's auto-reply settings, with defaults if never saved
+func GetMyChatSettings(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		shop, ok := findMyShop(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		settings := model.ShopChatSettings{ShopID: shop.ID, Timezone: autoreply.DefaultTimezone}
+		db.Where("shop_id = ?", shop.ID).First(&settings)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Chat settings retrieved",
+			"data": gin.H{
+				"settings": settings,
+				"is_open":  autoreply.IsOpen(settings, time.Now()),
+			},
+		})
+	}
+}
+
+// UpdateMyChatSettings saves the shop's greeting, away message and operating hours
+func UpdateMyChatSettings(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		shop, ok := findMyShop(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			GreetingEnabled bool                   `json:"greeting_enabled"`
+			GreetingMessage string                 `json:"greeting_message" binding:"max=1000"`
+			AwayEnabled     bool                   `json:"away_enabled"`
+			AwayMessage     string                 `json:"away_message" binding:"max=1000"`
+			Timezone        string                 `json:"timezone"`
+			OperatingHours  []model.OperatingHours `json:"operating_hours"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. Messages are limited to 1000 characters",
+			})
+			return
+		}
+		if msg := validateChatSettings(input.Timezone, input.OperatingHours); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": msg,
+			})
+			return
+		}
+		if (input.GreetingEnabled && strings.TrimSpace(input.GreetingMessage) == "") ||
+			(input.AwayEnabled && strings.TrimSpace(input.AwayMessage) == "") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Enabled auto-replies need a message",
+			})
+			return
+		}
+
+		settings := model.ShopChatSettings{ShopID: shop.ID}
+		db.Where("shop_id = ?", shop.ID).First(&settings)
+		before := settings
+
+		settings.GreetingEnabled = input.GreetingEnabled
+		settings.GreetingMessage = input.GreetingMessage
+		settings.AwayEnabled = input.AwayEnabled
+		settings.AwayMessage = input.AwayMessage
+		settings.Timezone = input.Timezone
+		if settings.Timezone == "" {
+			settings.Timezone = autoreply.DefaultTimezone
+		}
+		settings.OperatingHours = input.OperatingHours
+
+		if err := db.Save(&settings).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to save chat settings",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Update("shop_chat_settings", settings.ID).Before(before).After(settings).Success("Shop chat settings updated"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Chat settings saved",
+			"data": gin.H{
+				"settings": settings,
+				"is_open":  autoreply.IsOpen(settings, time.Now()),
+			},
+		})
+	}
+}
+
+// GetMyQuickReplies lists the shop's quick-reply templates
+func GetMyQuickReplies(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		shop, ok := findMyShop(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var replies []model.QuickReply
+		if err := db.Where("shop_id = ?", shop.ID).Order("position ASC, id ASC").Find(&replies).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve quick replies",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Quick replies retrieved",
+			"data":    replies,
+		})
+	}
+}
+
+// CreateMyQuickReply adds a quick-reply template
+func CreateMyQuickReply(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		shop, ok := findMyShop(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Title    string `json:"title" binding:"required,max=100"`
+			Content  string `json:"content" binding:"required,max=2000"`
+			Position int    `json:"position"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. title (max 100) and content (max 2000) are required",
+			})
+			return
+		}
+
+		var count int64
+		db.Model(&model.QuickReply{}).Where("shop_id = ?", shop.ID).Count(&count)
+		if count >= maxQuickReplies {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "A shop can have at most " + strconv.Itoa(maxQuickReplies) + " quick replies",
+			})
+			return
+		}
+
+		reply := model.QuickReply{
+			ShopID:   shop.ID,
+			Title:    input.Title,
+			Content:  input.Content,
+			Position: input.Position,
+		}
+		if err := db.Create(&reply).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to create quick reply",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Create("quick_reply", reply.ID).After(reply).Success("Quick reply created"))
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"message": "Quick reply created",
+			"data":    reply,
+		})
+	}
+}
+
+// UpdateMyQuickReply edits a quick-reply template
+func UpdateMyQuickReply(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		reply, ok := findMyQuickReply(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Title    *string `json:"title" binding:"omitempty,min=1,max=100"`
+			Content  *string `json:"content" binding:"omitempty,min=1,max=2000"`
+			Position *int    `json:"position"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. title is limited to 100 and content to 2000 characters",
+			})
+			return
+		}
+
+		before := reply
+		if input.Title != nil {
+			reply.Title = *input.Title
+		}
+		if input.Content != nil {
+			reply.Content = *input.Content
+		}
+		if input.Position != nil {
+			reply.Position = *input.Position
+		}
+
+		if err := db.Save(&reply).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to update quick reply",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Update("quick_reply", reply.ID).Before(before).After(reply).Success("Quick reply updated"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Quick reply updated",
+			"data":    reply,
+		})
+	}
+}
+
+// DeleteMyQuickReply removes a quick-reply template
+func DeleteMyQuickReply(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		reply, ok := findMyQuickReply(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		if err := db.Delete(&reply).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to delete quick reply",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Delete("quick_reply", reply.ID).Before(reply).Success("Quick reply deleted"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Quick reply deleted",
+		})
+	}
+}
+
+// GetChatQuickReplies returns the shop's quick replies with placeholders filled in for this chat,
+// ready to send with SendMessage
+func GetChatQuickReplies(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		chat, ok := findMyChat(c, db, userData.ID)
+		if !ok {
+			return
+		}
+		if chat.ShopID == nil || !chatroom.IsTeam(db, *chat.ShopID, userData.ID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Quick replies are only available to the shop's team",
+			})
+			return
+		}
+		var shop model.Shop
+		if err := db.First(&shop, *chat.ShopID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Shop not found",
+			})
+			return
+		}
+
+		var replies []model.QuickReply
+		if err := db.Where("shop_id = ?", shop.ID).Order("position ASC, id ASC").Find(&replies).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve quick replies",
+			})
+			return
+		}
+
+		vars := chatTemplateVars(db, chat, chatroom.Counterpart(db, chat, userData.ID), shop)
+		for i := range replies {
+			replies[i].Content = autoreply.Render(replies[i].Content, vars)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Quick replies retrieved",
+			"data":    replies,
+		})
+	}
+}
+
+func findMyQuickReply(c *gin.Context, db *gorm.DB, userID uint) (model.QuickReply, bool) {
+	replyID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	var reply model.QuickReply
+	if err := db.Joins("JOIN shops ON shops.id = quick_replies.shop_id").
+		Where("quick_replies.id = ? AND shops.user_id = ?", replyID, userID).
+		First(&reply).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Quick reply not found",
+		})
+		return reply, false
+	}
+	return reply, true
+}
+
+func validateChatSettings(timezone string, hours []model.OperatingHours) string {
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return "Unknown timezone " + timezone + ", use an IANA name like Asia/Jakarta"
+		}
+	}
+	if len(hours) > 21 {
+		return "At most 21 operating hour windows are allowed"
+	}
+	for _, h := range hours {
+		if h.Day < time.Sunday || h.Day > time.Saturday {
+			return "Operating hours day must be 0 (Sunday) to 6 (Saturday)"
+		}
+		opens, err := autoreply.ParseClock(h.Open)
+		if err != nil {
+			return err.Error()
+		}
+		closes, err := autoreply.ParseClock(h.Close)
+		if err != nil {
+			return err.Error()
+		}
+		if opens == closes {
+			return "Operating hours must open and close at different times"
+		}
+	}
+	return ""
+}
+
+// chatTemplateVars collects the placeholder values for a chat with the given buyer
+func chatTemplateVars(db *gorm.DB, chat model.Chat, buyerID uint, shop model.Shop) autoreply.Vars {
+	vars := autoreply.Vars{ShopName: shop.Name}
+
+	var buyer model.User
+	if err := db.Select("id", "first_name", "username").First(&buyer, buyerID).Error; err == nil {
+		vars.BuyerName = autoreply.BuyerName(buyer)
+	}
+	if chat.ProductID.Valid {
+		var product model.Product
+		if err := db.Select("id", "name").First(&product, chat.ProductID.Int64).Error; err == nil {
+			vars.ProductName = product.Name
+		}
+	}
+	return vars
+}
+
+// sendAutoReply posts the shop's greeting or away message into a shop chat on the owner's behalf when
+// the buyer writes and that reply is enabled. Replies are rate-limited per chat.
+func sendAutoReply(db *gorm.DB, chat model.Chat, buyerID uint, kind string) {
+	if chat.ShopID == nil || chatroom.IsTeam(db, *chat.ShopID, buyerID) {
+		return
+	}
+
+	var shop model.Shop
+	if err := db.First(&shop, *chat.ShopID).Error; err != nil {
+		return
+	}
+	sellerID := shop.UserID
+	var settings model.ShopChatSettings
+	if err := db.Where("shop_id = ?", shop.ID).First(&settings).Error; err != nil {
+		return
+	}
+
+	var template string
+	switch kind {
+	case autoreply.Greeting:
+		if !settings.GreetingEnabled {
+			return
+		}
+		template = settings.GreetingMessage
+	case autoreply.Away:
+		if !settings.AwayEnabled || autoreply.IsOpen(settings, time.Now()) {
+			return
+		}
+		template = settings.AwayMessage
+	}
+	if strings.TrimSpace(template) == "" || !autoreply.Due(kind, chat.ID) {
+		return
+	}
+
+	content := autoreply.Render(template, chatTemplateVars(db, chat, buyerID, shop))
+	var message model.Message
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		message, err = postChatMessage(tx, chat.ID, sellerID, model.MessageSystem, content, model.SystemPayload{Event: kind})
+		return err
+	}); err != nil {
+		logrus.Errorf("Failed to send %s in chat %d: %v", kind, chat.ID, err)
+		return
+	}
+
+	websockets.PushChatMessage(db, websockets.EventMessageNew, message)
+}
@@ -0,0 +1,379 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/autoreply"
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/websockets"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxShopStaff = 20
+
+// GetShopInbox lists the chats addressed to a shop the user owns or works for.
+// ?assigned=me|unassigned narrows the list; ?shop_id= picks the shop for staff of several shops.
+func GetShopInbox(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		shop, ok := findTeamShop(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		query := db.Where("shop_id = ? AND id IN (?)", shop.ID, chatroom.Joined(db, userData.ID))
+		switch c.DefaultQuery("assigned", "all") {
+		case "me":
+			query = query.Where("assigned_to = ?", userData.ID)
+		case "unassigned":
+			query = query.Where("assigned_to IS NULL")
+		case "all":
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "assigned must be me, unassigned or all",
+			})
+			return
+		}
+
+		var chats []model.Chat
+		if err := query.
+			Preload("User1").
+			Preload("User2").
+			Preload("Product").
+			Preload("Shop").
+			Preload("Participants.User").
+			Order("updated_at DESC").
+			Find(&chats).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve chats",
+			})
+			return
+		}
+
+		populateChats(db, chats, userData.ID)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"shop":  shop,
+				"chats": chats,
+				"count": len(chats),
+			},
+		})
+	}
+}
+
+// GetMyShopStaff lists the users who can answer the shop's chats besides the owner
+func GetMyShopStaff(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		shop, ok := findMyShop(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var staff []model.ShopStaff
+		if err := db.Preload("User").Where("shop_id = ?", shop.ID).Order("id ASC").Find(&staff).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve staff",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Staff retrieved",
+			"data":    staff,
+		})
+	}
+}
+
+// AddMyShopStaff gives a user access to the shop's inbox, including every existing shop chat
+func AddMyShopStaff(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		shop, ok := findMyShop(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			UserID uint   `json:"user_id"`
+			Email  string `json:"email"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil || (input.UserID == 0 && input.Email == "") {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Provide user_id or email",
+			})
+			return
+		}
+
+		var user model.User
+		query := db.Where("id = ?", input.UserID)
+		if input.UserID == 0 {
+			query = db.Where("email = ?", strings.ToLower(strings.TrimSpace(input.Email)))
+		}
+		if err := query.First(&user).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "User not found",
+			})
+			return
+		}
+		if user.ID == shop.UserID {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "The shop owner is already on the team",
+			})
+			return
+		}
+
+		var count int64
+		db.Model(&model.ShopStaff{}).Where("shop_id = ?", shop.ID).Count(&count)
+		if count >= maxShopStaff {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "A shop can have at most " + strconv.Itoa(maxShopStaff) + " staff",
+			})
+			return
+		}
+		db.Model(&model.ShopStaff{}).Where("shop_id = ? AND user_id = ?", shop.ID, user.ID).Count(&count)
+		if count > 0 {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "User is already on the team",
+			})
+			return
+		}
+
+		staff := model.ShopStaff{ShopID: shop.ID, UserID: user.ID}
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&staff).Error; err != nil {
+				return err
+			}
+			var chatIDs []uint
+			if err := tx.Model(&model.Chat{}).Where("shop_id = ?", shop.ID).Pluck("id", &chatIDs).Error; err != nil {
+				return err
+			}
+			for _, chatID := range chatIDs {
+				if err := chatroom.Join(tx, chatID, user.ID, model.ChatRoleStaff); err != nil {
+					return err
+				}
+			}
+			return nil
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to add staff",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Create("shop_staff", staff.ID).After(staff).Success("Shop staff added"))
+
+		staff.User = user
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"message": "Staff added",
+			"data":    staff,
+		})
+	}
+}
+
+// RemoveMyShopStaff takes a user off the shop's team. They leave the shop's chats and their
+// assigned chats go back to the unassigned queue.
+func RemoveMyShopStaff(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		shop, ok := findMyShop(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		userID, _ := strconv.ParseUint(c.Param("user_id"), 10, 32)
+
+		var staff model.ShopStaff
+		if err := db.Where("shop_id = ? AND user_id = ?", shop.ID, userID).First(&staff).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Staff member not found",
+			})
+			return
+		}
+
+		shopChats := db.Model(&model.Chat{}).Select("id").Where("shop_id = ?", shop.ID)
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Delete(&staff).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("user_id = ? AND role = ? AND chat_id IN (?)", staff.UserID, model.ChatRoleStaff, shopChats).
+				Delete(&model.ChatParticipant{}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&model.Chat{}).
+				Where("shop_id = ? AND assigned_to = ?", shop.ID, staff.UserID).
+				Update("assigned_to", nil).Error
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to remove staff",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Delete("shop_staff", staff.ID).Before(staff).Success("Shop staff removed"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Staff removed",
+		})
+	}
+}
+
+// AssignChat hands a shop chat to a team member, or back to the unassigned queue with user_id 0.
+// Buyers' messages are then addressed to the assignee.
+func AssignChat(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		chat, ok := findMyChat(c, db, userData.ID)
+		if !ok {
+			return
+		}
+		if chat.ShopID == nil || !chatroom.IsTeam(db, *chat.ShopID, userData.ID) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"message": "Only the shop's team can assign this chat",
+			})
+			return
+		}
+
+		var input struct {
+			UserID uint `json:"user_id"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. Provide user_id, or 0 to unassign",
+			})
+			return
+		}
+
+		var assignee *uint
+		content := "Chat unassigned"
+		if input.UserID != 0 {
+			var user model.User
+			if !chatroom.IsTeam(db, *chat.ShopID, input.UserID) || db.First(&user, input.UserID).Error != nil {
+				c.JSON(http.StatusBadRequest, gin.H{
+					"success": false,
+					"message": "Chats can only be assigned to the shop's owner or staff",
+				})
+				return
+			}
+			assignee = &user.ID
+			content = "Chat assigned to " + autoreply.BuyerName(user)
+		}
+
+		before := chat
+		var notice model.Message
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&chat).Update("assigned_to", assignee).Error; err != nil {
+				return err
+			}
+			chat.AssignedTo = assignee
+			var err error
+			notice, err = postChatMessage(tx, chat.ID, userData.ID, model.MessageSystem, content,
+				model.SystemPayload{Event: "chat.assigned", UserID: assignee})
+			return err
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to assign chat",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Update("chat", chat.ID).Before(before).After(chat).Success(content))
+
+		websockets.PushChatMessage(db, websockets.EventMessageNew, notice)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": content,
+			"data":    chat,
+		})
+	}
+}
+
+// findTeamShop picks the shop whose inbox the user works in: ?shop_id= if given, otherwise the shop
+// they own, otherwise the first shop they are staff of
+func findTeamShop(c *gin.Context, db *gorm.DB, userID uint) (model.Shop, bool) {
+	var shop model.Shop
+	var err error
+	if shopID, _ := strconv.ParseUint(c.Query("shop_id"), 10, 32); shopID != 0 {
+		err = gorm.ErrRecordNotFound
+		if chatroom.IsTeam(db, uint(shopID), userID) {
+			err = db.First(&shop, shopID).Error
+		}
+	} else if err = db.Where("user_id = ?", userID).First(&shop).Error; err != nil {
+		err = db.Where("id IN (?)", db.Model(&model.ShopStaff{}).Select("shop_id").Where("user_id = ?", userID)).
+			Order("id ASC").First(&shop).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "You are not on any shop's team",
+		})
+		return shop, false
+	}
+	return shop, true
+}
@@ -0,0 +1,471 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/webhook"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetMyWebhooks lists the webhook endpoints registered for the user's shop
+func GetMyWebhooks(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		shop, ok := findMyShop(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var endpoints []model.WebhookEndpoint
+		if err := db.Where("shop_id = ?", shop.ID).Order("created_at ASC").Find(&endpoints).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve webhooks",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"endpoints":   endpoints,
+				"event_types": webhook.EventTypes,
+			},
+		})
+	}
+}
+
+// CreateMyWebhook registers a webhook endpoint; the signing secret is only returned here and on rotation
+func CreateMyWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		shop, ok := findMyShop(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			URL         string   `json:"url" binding:"required"`
+			EventTypes  []string `json:"event_types" binding:"required,min=1"`
+			Description string   `json:"description"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. Required fields: url, event_types",
+			})
+			return
+		}
+		if msg := validateWebhookInput(input.URL, input.EventTypes); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": msg,
+			})
+			return
+		}
+
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to generate webhook secret",
+			})
+			return
+		}
+
+		endpoint := model.WebhookEndpoint{
+			ShopID:      shop.ID,
+			URL:         input.URL,
+			Secret:      secret,
+			EventTypes:  input.EventTypes,
+			Description: input.Description,
+			IsActive:    true,
+		}
+		if err := db.Create(&endpoint).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to create webhook",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Create("webhook_endpoint", endpoint.ID).After(endpoint).Success("Webhook endpoint created"))
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"message": "Webhook created. Store the secret now, it will not be shown again.",
+			"data": gin.H{
+				"endpoint": endpoint,
+				"secret":   secret,
+			},
+		})
+	}
+}
+
+// UpdateMyWebhook changes the URL, subscriptions or active flag; re-enabling clears the failure count
+func UpdateMyWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		endpoint, ok := findMyWebhook(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			URL         *string  `json:"url"`
+			EventTypes  []string `json:"event_types"`
+			Description *string  `json:"description"`
+			IsActive    *bool    `json:"is_active"`
+		}
+		c.ShouldBindJSON(&input)
+
+		old := endpoint
+		newURL := endpoint.URL
+		if input.URL != nil {
+			newURL = *input.URL
+		}
+		newTypes := []string(endpoint.EventTypes)
+		if input.EventTypes != nil {
+			newTypes = input.EventTypes
+		}
+		if msg := validateWebhookInput(newURL, newTypes); msg != "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": msg,
+			})
+			return
+		}
+
+		endpoint.URL = newURL
+		endpoint.EventTypes = newTypes
+		if input.Description != nil {
+			endpoint.Description = *input.Description
+		}
+		if input.IsActive != nil && *input.IsActive != endpoint.IsActive {
+			endpoint.IsActive = *input.IsActive
+			if endpoint.IsActive {
+				endpoint.FailureCount = 0
+				endpoint.DisabledAt = nil
+				endpoint.DisabledReason = ""
+			}
+		}
+		if err := db.Save(&endpoint).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to update webhook",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Update("webhook_endpoint", endpoint.ID).Before(old).After(endpoint).Success("Webhook endpoint updated"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Updated",
+			"data":    endpoint,
+		})
+	}
+}
+
+// DeleteMyWebhook removes a webhook endpoint; pending deliveries to it are dropped by the worker
+func DeleteMyWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		endpoint, ok := findMyWebhook(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		if err := db.Delete(&endpoint).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to delete webhook",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Delete("webhook_endpoint", endpoint.ID).Before(endpoint).Success("Webhook endpoint deleted"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Webhook deleted",
+		})
+	}
+}
+
+// RotateMyWebhookSecret replaces the signing secret and returns the new one
+func RotateMyWebhookSecret(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		endpoint, ok := findMyWebhook(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		secret, err := webhook.NewSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to generate webhook secret",
+			})
+			return
+		}
+		if err := db.Model(&endpoint).Update("secret", secret).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to rotate secret",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Update("webhook_endpoint", endpoint.ID).Success("Webhook secret rotated"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Secret rotated. Store the secret now, it will not be shown again.",
+			"data": gin.H{
+				"secret": secret,
+			},
+		})
+	}
+}
+
+// GetMyWebhookDeliveries returns the delivery log of an endpoint, newest first
+func GetMyWebhookDeliveries(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		endpoint, ok := findMyWebhook(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+
+		query := db.Model(&model.WebhookDelivery{}).Where("endpoint_id = ?", endpoint.ID)
+		if status := c.Query("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var total int64
+		query.Count(&total)
+
+		var deliveries []model.WebhookDelivery
+		if err := query.Order("id DESC").
+			Offset((page - 1) * limit).
+			Limit(limit).
+			Find(&deliveries).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve deliveries",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"deliveries": deliveries,
+				"total":      total,
+				"page":       page,
+				"limit":      limit,
+			},
+		})
+	}
+}
+
+// RedeliverMyWebhook queues a past delivery again with its original payload
+func RedeliverMyWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		endpoint, ok := findMyWebhook(c, db, userData.ID)
+		if !ok {
+			return
+		}
+		if !endpoint.IsActive {
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"message": "Webhook is disabled. Enable it before redelivering.",
+			})
+			return
+		}
+
+		deliveryID, _ := strconv.ParseUint(c.Param("delivery_id"), 10, 32)
+		var original model.WebhookDelivery
+		if err := db.Where("id = ? AND endpoint_id = ?", deliveryID, endpoint.ID).First(&original).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Delivery not found",
+			})
+			return
+		}
+
+		delivery, err := webhook.Redeliver(db, original)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to queue redelivery",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Create("webhook_delivery", delivery.ID).After(delivery).Success("Webhook redelivery queued"))
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": "Redelivery queued",
+			"data":    delivery,
+		})
+	}
+}
+
+// TestMyWebhook sends a ping event to the endpoint and returns the result
+func TestMyWebhook(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		endpoint, ok := findMyWebhook(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		delivery, err := webhook.Ping(db, endpoint)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to send test event",
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": delivery.Status == model.WebhookDeliverySuccess,
+			"message": "Test event sent",
+			"data":    delivery,
+		})
+	}
+}
+
+// validateWebhookInput returns an error message when the URL or event types are not acceptable
+func validateWebhookInput(rawURL string, eventTypes []string) string {
+	if err := webhook.ValidateURL(rawURL); errors.Is(err, webhook.ErrBlockedAddress) {
+		return "URL must not point to a local or private network address"
+	} else if err != nil {
+		return err.Error()
+	}
+	if len(eventTypes) == 0 {
+		return "Subscribe to at least one event type"
+	}
+	for _, t := range eventTypes {
+		if !slices.Contains(webhook.EventTypes, t) {
+			return "Unknown event type: " + t
+		}
+	}
+	return ""
+}
+
+// findMyShop loads the user's shop and writes a 404 when they don't have one
+func findMyShop(c *gin.Context, db *gorm.DB, userID uint) (model.Shop, bool) {
+	var shop model.Shop
+	if err := db.Where("user_id = ?", userID).First(&shop).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "You don't have a shop yet",
+		})
+		return shop, false
+	}
+	return shop, true
+}
+
+// findMyWebhook loads the endpoint from the :id param and writes a 404 when it isn't on the user's shop
+func findMyWebhook(c *gin.Context, db *gorm.DB, userID uint) (model.WebhookEndpoint, bool) {
+	endpointID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	var endpoint model.WebhookEndpoint
+	if err := db.Joins("JOIN shops ON shops.id = webhook_endpoints.shop_id").
+		Where("webhook_endpoints.id = ? AND shops.user_id = ?", endpointID, userID).
+		First(&endpoint).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Webhook not found",
+		})
+		return endpoint, false
+	}
+	return endpoint, true
+}
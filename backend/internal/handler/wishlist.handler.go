@@ -23,8 +23,18 @@ func GetMyWishlist(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		query := db.Where("user_id = ?", userData.ID)
+		// Optional: filter by collection (collection_id=0 for items outside any collection)
+		if collectionID := c.Query("collection_id"); collectionID != "" {
+			if collectionID == "0" {
+				query = query.Where("collection_id IS NULL")
+			} else {
+				query = query.Where("collection_id = ?", collectionID)
+			}
+		}
+
 		var wishlistItems []model.WishlistItem
-		if err := db.Where("user_id = ?", userData.ID).
+		if err := query.
 			Preload("Product").
 			Preload("Product.Shop").
 			Preload("Product.Category").
@@ -62,8 +72,12 @@ func AddToWishlist(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		var input struct {
-			ProductID uint   `json:"product_id" binding:"required"`
-			Notes     string `json:"notes"`
+			ProductID         uint    `json:"product_id" binding:"required"`
+			Notes             string  `json:"notes"`
+			NotifyPriceDrop   bool    `json:"notify_price_drop"`
+			NotifyBackInStock bool    `json:"notify_back_in_stock"`
+			TargetPrice       float64 `json:"target_price"`
+			CollectionID      *uint   `json:"collection_id"`
 		}
 
 		if err := c.ShouldBindJSON(&input); err != nil {
@@ -85,10 +99,36 @@ func AddToWishlist(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		var product model.Product
+		if err := db.First(&product, input.ProductID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Product not found",
+			})
+			return
+		}
+
+		if input.CollectionID != nil {
+			var count int64
+			db.Model(&model.WishlistCollection{}).Where("id = ? AND user_id = ?", *input.CollectionID, userData.ID).Count(&count)
+			if count == 0 {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"message": "Collection not found",
+				})
+				return
+			}
+		}
+
 		wishlistItem := model.WishlistItem{
-			UserID:    userData.ID,
-			ProductID: input.ProductID,
-			Notes:     input.Notes,
+			CollectionID:      input.CollectionID,
+			UserID:            userData.ID,
+			ProductID:         input.ProductID,
+			Notes:             input.Notes,
+			NotifyPriceDrop:   input.NotifyPriceDrop,
+			NotifyBackInStock: input.NotifyBackInStock,
+			SavedPrice:        product.Price,
+			TargetPrice:       input.TargetPrice,
 		}
 
 		if err := db.Create(&wishlistItem).Error; err != nil {
@@ -110,7 +150,7 @@ func AddToWishlist(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-// UpdateWishlistItem updates wishlist item notes
+// UpdateWishlistItem updates wishlist item notes and alert settings
 func UpdateWishlistItem(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userData, err := helper.GetFirebaseUser(c)
@@ -124,7 +164,10 @@ func UpdateWishlistItem(db *gorm.DB) gin.HandlerFunc {
 
 		itemID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
 		var input struct {
-			Notes string `json:"notes"`
+			Notes             *string  `json:"notes"`
+			NotifyPriceDrop   *bool    `json:"notify_price_drop"`
+			NotifyBackInStock *bool    `json:"notify_back_in_stock"`
+			TargetPrice       *float64 `json:"target_price"` // 0 falls back to the saved price
 		}
 		c.ShouldBindJSON(&input)
 
@@ -138,7 +181,19 @@ func UpdateWishlistItem(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		old := item
-		item.Notes = input.Notes
+		if input.Notes != nil {
+			item.Notes = *input.Notes
+		}
+		if input.NotifyPriceDrop != nil {
+			item.NotifyPriceDrop = *input.NotifyPriceDrop
+		}
+		if input.NotifyBackInStock != nil {
+			item.NotifyBackInStock = *input.NotifyBackInStock
+		}
+		if input.TargetPrice != nil && *input.TargetPrice != item.TargetPrice {
+			item.TargetPrice = *input.TargetPrice
+			item.LastAlertPrice = 0 // New threshold, allow a fresh alert
+		}
 		db.Save(&item)
 
 		audit.Log(c, db, userData.ID, audit.Update("wishlist_item", item.ID).Before(old).After(item).Success("Updated wishlist item"))
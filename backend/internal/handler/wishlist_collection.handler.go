@@ -0,0 +1,425 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetMyWishlistCollections lists the user's named wishlist collections with item counts
+func GetMyWishlistCollections(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var collections []model.WishlistCollection
+		if err := db.Where("user_id = ?", userData.ID).
+			Order("created_at ASC").
+			Find(&collections).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to retrieve collections",
+			})
+			return
+		}
+
+		for i := range collections {
+			var count int64
+			db.Model(&model.WishlistItem{}).Where("collection_id = ?", collections[i].ID).Count(&count)
+			collections[i].ItemCount = int(count)
+		}
+
+		// Items not in any collection
+		var uncategorized int64
+		db.Model(&model.WishlistItem{}).Where("user_id = ? AND collection_id IS NULL", userData.ID).Count(&uncategorized)
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"collections":         collections,
+				"count":               len(collections),
+				"uncategorized_count": uncategorized,
+			},
+		})
+	}
+}
+
+// CreateWishlistCollection creates a new named collection
+func CreateWishlistCollection(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		var input struct {
+			Name        string `json:"name" binding:"required,max=100"`
+			Description string `json:"description"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input. Required fields: name",
+			})
+			return
+		}
+
+		collection := model.WishlistCollection{
+			UserID:      userData.ID,
+			Name:        input.Name,
+			Description: input.Description,
+		}
+		if err := db.Create(&collection).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to create collection",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Create("wishlist_collection", collection.ID).After(collection).Success("Wishlist collection created"))
+
+		c.JSON(http.StatusCreated, gin.H{
+			"success": true,
+			"message": "Collection created",
+			"data":    collection,
+		})
+	}
+}
+
+// UpdateWishlistCollection renames a collection or changes its description
+func UpdateWishlistCollection(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		collection, ok := findMyWishlistCollection(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		var input struct {
+			Name        *string `json:"name"`
+			Description *string `json:"description"`
+		}
+		c.ShouldBindJSON(&input)
+
+		old := collection
+		if input.Name != nil && *input.Name != "" {
+			collection.Name = *input.Name
+		}
+		if input.Description != nil {
+			collection.Description = *input.Description
+		}
+		db.Save(&collection)
+
+		audit.Log(c, db, userData.ID, audit.Update("wishlist_collection", collection.ID).Before(old).After(collection).Success("Wishlist collection updated"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Updated",
+			"data":    collection,
+		})
+	}
+}
+
+// DeleteWishlistCollection deletes a collection; its items move back to the default list
+func DeleteWishlistCollection(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		collection, ok := findMyWishlistCollection(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&model.WishlistItem{}).
+				Where("collection_id = ?", collection.ID).
+				Update("collection_id", nil).Error; err != nil {
+				return err
+			}
+			// Release the share token so old links stop working
+			if err := tx.Model(&collection).Update("share_token", nil).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&collection).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "Failed to delete collection",
+			})
+			return
+		}
+
+		audit.Log(c, db, userData.ID, audit.Delete("wishlist_collection", collection.ID).Before(collection).Success("Wishlist collection deleted"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Collection deleted",
+		})
+	}
+}
+
+// MoveWishlistItem moves a wishlist item into another collection (null = default list)
+func MoveWishlistItem(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		itemID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+		var input struct {
+			CollectionID *uint `json:"collection_id"`
+		}
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "Invalid input",
+			})
+			return
+		}
+
+		var item model.WishlistItem
+		if err := db.Where("id = ? AND user_id = ?", itemID, userData.ID).First(&item).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Item not found",
+			})
+			return
+		}
+
+		if input.CollectionID != nil && *input.CollectionID != 0 {
+			var count int64
+			db.Model(&model.WishlistCollection{}).Where("id = ? AND user_id = ?", *input.CollectionID, userData.ID).Count(&count)
+			if count == 0 {
+				c.JSON(http.StatusNotFound, gin.H{
+					"success": false,
+					"message": "Collection not found",
+				})
+				return
+			}
+		} else {
+			input.CollectionID = nil
+		}
+
+		old := item
+		item.CollectionID = input.CollectionID
+		db.Model(&item).Update("collection_id", item.CollectionID)
+
+		audit.Log(c, db, userData.ID, audit.Update("wishlist_item", item.ID).Before(old).After(item).Success("Moved wishlist item"))
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Item moved",
+			"data":    item,
+		})
+	}
+}
+
+// ShareWishlistCollection publishes a collection under an unguessable share token
+func ShareWishlistCollection(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		collection, ok := findMyWishlistCollection(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		if !collection.IsShared() {
+			token, err := util.GenerateSecureToken(24)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"message": "Failed to generate share link",
+				})
+				return
+			}
+			now := time.Now()
+			old := collection
+			collection.ShareToken = &token
+			collection.SharedAt = &now
+			if err := db.Save(&collection).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{
+					"success": false,
+					"message": "Failed to share collection",
+				})
+				return
+			}
+			audit.Log(c, db, userData.ID, audit.Update("wishlist_collection", collection.ID).Before(old).After(collection).Success("Wishlist collection shared"))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Collection shared",
+			"data": gin.H{
+				"share_token": *collection.ShareToken,
+				"share_path":  "/wishlist/shared/" + *collection.ShareToken,
+			},
+		})
+	}
+}
+
+// UnshareWishlistCollection revokes a collection's share link
+func UnshareWishlistCollection(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userData, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "Unauthorized",
+			})
+			return
+		}
+
+		collection, ok := findMyWishlistCollection(c, db, userData.ID)
+		if !ok {
+			return
+		}
+
+		if collection.IsShared() {
+			old := collection
+			db.Model(&collection).Updates(map[string]interface{}{
+				"share_token": nil,
+				"shared_at":   nil,
+			})
+			audit.Log(c, db, userData.ID, audit.Update("wishlist_collection", collection.ID).Before(old).Success("Wishlist collection unshared"))
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "Share link revoked",
+		})
+	}
+}
+
+// GetSharedWishlistCollection - Public read-only view of a shared collection
+// Shows live price/stock and hides products that were removed or deactivated
+func GetSharedWishlistCollection(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Param("token")
+		if token == "" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Collection not found",
+			})
+			return
+		}
+
+		var collection model.WishlistCollection
+		if err := db.Preload("User").Where("share_token = ?", token).First(&collection).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"message": "Collection not found",
+			})
+			return
+		}
+
+		var items []model.WishlistItem
+		db.Where("collection_id = ?", collection.ID).
+			Preload("Product").
+			Preload("Product.Shop").
+			Preload("Product.Images").
+			Order("created_at DESC").
+			Find(&items)
+
+		type sharedItem struct {
+			ProductID uint    `json:"product_id"`
+			Name      string  `json:"name"`
+			Slug      string  `json:"slug"`
+			ImageURL  string  `json:"image_url"`
+			Price     float64 `json:"price"`
+			Stock     int     `json:"stock"`
+			InStock   bool    `json:"in_stock"`
+			ShopName  string  `json:"shop_name"`
+		}
+		visible := make([]sharedItem, 0, len(items))
+		for _, item := range items {
+			// Soft-deleted products are not preloaded; inactive ones are hidden too
+			if item.Product.ID == 0 || !item.Product.IsActive {
+				continue
+			}
+			visible = append(visible, sharedItem{
+				ProductID: item.Product.ID,
+				Name:      item.Product.Name,
+				Slug:      item.Product.Slug,
+				ImageURL:  item.Product.ImageURL,
+				Price:     item.Product.Price,
+				Stock:     item.Product.Stock,
+				InStock:   item.Product.Stock > 0,
+				ShopName:  item.Product.Shop.Name,
+			})
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data": gin.H{
+				"name":        collection.Name,
+				"description": collection.Description,
+				"owner_name":  collection.User.FirstName,
+				"shared_at":   collection.SharedAt,
+				"items":       visible,
+				"count":       len(visible),
+			},
+		})
+	}
+}
+
+// findMyWishlistCollection loads the collection from the :id param and writes a 404 when it isn't the user's
+func findMyWishlistCollection(c *gin.Context, db *gorm.DB, userID uint) (model.WishlistCollection, bool) {
+	collectionID, _ := strconv.ParseUint(c.Param("id"), 10, 32)
+
+	var collection model.WishlistCollection
+	if err := db.Where("id = ? AND user_id = ?", collectionID, userID).First(&collection).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"success": false,
+			"message": "Collection not found",
+		})
+		return collection, false
+	}
+	return collection, true
+}
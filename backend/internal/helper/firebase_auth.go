@@ -8,10 +8,12 @@ import (
 
 	"firebase.google.com/go/auth"
 	"github.com/faiz-muttaqin/lgs/backend/internal/database"
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
 	"github.com/faiz-muttaqin/lgs/backend/internal/model"
 	"github.com/faiz-muttaqin/lgs/backend/pkg/types"
 	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 var FirebaseAuth *auth.Client
@@ -125,7 +127,7 @@ func GetFirebaseUser(c *gin.Context) (*model.User, error) {
 		usrNew.RoleID = 1
 		usrNew.Status = "active"
 	}
-	if err = database.DB.Preload("UserRole.AbilityRules").Where("email = ?", firebaseAuthData.Email).FirstOrCreate(&usrNew).Error; err != nil {
+	if err = registerUser(&usrNew); err != nil {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 	return &usrNew, nil
@@ -224,8 +226,22 @@ func GetFirebaseUser2(r *http.Request) (*model.User, error) {
 		usrNew.RoleID = 1
 		usrNew.Status = "active"
 	}
-	if err = database.DB.Preload("UserRole.AbilityRules").Where("email = ?", firebaseAuthData.Email).FirstOrCreate(&usrNew).Error; err != nil {
+	if err = registerUser(&usrNew); err != nil {
 		return nil, fmt.Errorf("database error: %w", err)
 	}
 	return &usrNew, nil
 }
+
+// registerUser creates the user if the email is new and publishes UserRegistered in the same transaction
+func registerUser(usr *model.User) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Preload("UserRole.AbilityRules").Where("email = ?", usr.Email).FirstOrCreate(usr)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil // Already registered (concurrent first login)
+		}
+		return events.Publish(tx, events.UserRegistered{User: *usr})
+	})
+}
@@ -0,0 +1,174 @@
+// Package llmusage meters language model calls: every call made through For is checked against the
+// feature's daily token budget and recorded in llm_usages for the admin usage report.
+package llmusage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/llm"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Features using the LLM; each has its own budget, LLM_BUDGET_<FEATURE>
+const (
+	FeatureChatDraft   = "chat_draft"
+	FeatureProductCopy = "product_copy"
+	FeatureSearch      = "semantic_search" // Embedding search queries
+	FeatureSearchIndex = "semantic_index"  // Embedding products for search, so queries can't starve indexing
+	FeatureListing     = "listing_quality"
+)
+
+// Features lists the known features for the usage report
+var Features = []string{FeatureChatDraft, FeatureProductCopy, FeatureSearch, FeatureSearchIndex, FeatureListing}
+
+// ErrBudgetExceeded is returned once a feature has used its daily token budget
+var ErrBudgetExceeded = errors.New("the daily LLM token budget for this feature is used up")
+
+// Budget is the daily token budget (prompt and answer) of a feature from LLM_BUDGET_<FEATURE>, falling
+// back to LLM_BUDGET_DEFAULT; 0 means unlimited
+func Budget(feature string) int {
+	return max(util.Getenv("LLM_BUDGET_"+strings.ToUpper(feature), util.Getenv("LLM_BUDGET_DEFAULT", 0)), 0)
+}
+
+// UsedToday sums the tokens a feature used since midnight
+func UsedToday(db *gorm.DB, feature string) int {
+	now := time.Now()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	var used int
+	db.Model(&model.LLMUsage{}).
+		Where("feature = ? AND created_at >= ?", feature, midnight).
+		Select("COALESCE(SUM(tokens_in + tokens_out), 0)").
+		Scan(&used)
+	return used
+}
+
+// For returns the default client metered for a feature; userID is 0 for background jobs.
+// Without a configured provider every call fails with llm.ErrNotConfigured.
+func For(db *gorm.DB, feature string, userID uint) llm.Client {
+	return &metered{db: db, feature: feature, userID: userID}
+}
+
+type metered struct {
+	db      *gorm.DB
+	feature string
+	userID  uint
+}
+
+func (m *metered) Provider() string {
+	if c := llm.Default(); c != nil {
+		return c.Provider()
+	}
+	return ""
+}
+
+func (m *metered) Model() string {
+	if c := llm.Default(); c != nil {
+		return c.Model()
+	}
+	return ""
+}
+
+func (m *metered) EmbeddingModel() string {
+	if c := llm.Default(); c != nil {
+		return c.EmbeddingModel()
+	}
+	return ""
+}
+
+func (m *metered) Complete(ctx context.Context, prompt string, opts ...llm.Option) (llm.Response, error) {
+	var resp llm.Response
+	err := m.call(ctx, model.LLMOpComplete, func(ctx context.Context, c llm.Client) (int, int, error) {
+		var err error
+		resp, err = c.Complete(ctx, prompt, opts...)
+		return resp.TokensIn, resp.TokensOut, err
+	})
+	return resp, err
+}
+
+func (m *metered) Chat(ctx context.Context, messages []llm.Message, opts ...llm.Option) (llm.Response, error) {
+	var resp llm.Response
+	err := m.call(ctx, model.LLMOpChat, func(ctx context.Context, c llm.Client) (int, int, error) {
+		var err error
+		resp, err = c.Chat(ctx, messages, opts...)
+		return resp.TokensIn, resp.TokensOut, err
+	})
+	return resp, err
+}
+
+func (m *metered) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var vectors [][]float32
+	err := m.call(ctx, model.LLMOpEmbed, func(ctx context.Context, c llm.Client) (int, int, error) {
+		tokens := 0
+		for _, t := range texts {
+			tokens += llm.CountTokens(t)
+		}
+		var err error
+		vectors, err = c.Embed(ctx, texts)
+		return tokens, 0, err
+	})
+	return vectors, err
+}
+
+// call runs fn against the default client within the budget and LLM_TIMEOUT_S, and records it.
+// A panicking provider is turned into an error so a bad response cannot take the server down.
+func (m *metered) call(ctx context.Context, op string, fn func(context.Context, llm.Client) (int, int, error)) (err error) {
+	client := llm.Default()
+	if client == nil {
+		return llm.ErrNotConfigured
+	}
+	if budget := Budget(m.feature); budget > 0 && UsedToday(m.db, m.feature) >= budget {
+		return ErrBudgetExceeded
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(util.Getenv("LLM_TIMEOUT_S", 60))*time.Second)
+		defer cancel()
+	}
+
+	usage := model.LLMUsage{
+		Feature:   m.feature,
+		Operation: op,
+		Provider:  client.Provider(),
+		Model:     client.Model(),
+	}
+	if op == model.LLMOpEmbed {
+		usage.Model = client.EmbeddingModel()
+	}
+	if m.userID != 0 {
+		usage.UserID = &m.userID
+	}
+
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("LLM provider panicked: %v", r)
+		}
+		usage.LatencyMs = time.Since(start).Milliseconds()
+		usage.Success = err == nil
+		if err != nil {
+			usage.Error = truncate(err.Error(), 500)
+			logrus.Warnf("LLM %s for %s failed after %dms: %v", op, m.feature, usage.LatencyMs, err)
+		}
+		if dbErr := m.db.Create(&usage).Error; dbErr != nil {
+			logrus.Errorf("Failed to record LLM usage: %v", dbErr)
+		}
+	}()
+
+	usage.TokensIn, usage.TokensOut, err = fn(ctx, client)
+	return err
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -0,0 +1,108 @@
+package llmusage
+
+import (
+	"slices"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/llm"
+	"gorm.io/gorm"
+)
+
+// FeatureUsage totals a feature's calls in the report period
+type FeatureUsage struct {
+	Feature      string  `json:"feature"`
+	Calls        int64   `json:"calls"`
+	Failures     int64   `json:"failures"`
+	TokensIn     int64   `json:"tokens_in"`
+	TokensOut    int64   `json:"tokens_out"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+	BudgetPerDay int     `json:"budget_per_day"` // 0 is unlimited
+	UsedToday    int     `json:"used_today"`
+}
+
+// DailyUsage totals one feature's calls on one day
+type DailyUsage struct {
+	Day       time.Time `json:"day"`
+	Feature   string    `json:"feature"`
+	Calls     int64     `json:"calls"`
+	TokensIn  int64     `json:"tokens_in"`
+	TokensOut int64     `json:"tokens_out"`
+}
+
+// UserUsage totals the calls made on behalf of one user
+type UserUsage struct {
+	UserID uint  `json:"user_id"`
+	Calls  int64 `json:"calls"`
+	Tokens int64 `json:"tokens"`
+}
+
+// Report is the admin usage report
+type Report struct {
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Configured bool           `json:"configured"`
+	Provider   string         `json:"provider,omitempty"`
+	Model      string         `json:"model,omitempty"`
+	Features   []FeatureUsage `json:"features"`
+	Daily      []DailyUsage   `json:"daily"`
+	TopUsers   []UserUsage    `json:"top_users"`
+}
+
+// Summarize reports usage between from and to, optionally for one feature
+func Summarize(db *gorm.DB, from, to time.Time, feature string) (Report, error) {
+	r := Report{From: from, To: to}
+	if c := llm.Default(); c != nil {
+		r.Configured, r.Provider, r.Model = true, c.Provider(), c.Model()
+	}
+
+	scope := func() *gorm.DB {
+		q := db.Model(&model.LLMUsage{}).Where("created_at >= ? AND created_at < ?", from, to)
+		if feature != "" {
+			q = q.Where("feature = ?", feature)
+		}
+		return q
+	}
+
+	if err := scope().
+		Select("feature, COUNT(*) AS calls, "+
+			"SUM(CASE WHEN success = ? THEN 0 ELSE 1 END) AS failures, "+
+			"COALESCE(SUM(tokens_in), 0) AS tokens_in, COALESCE(SUM(tokens_out), 0) AS tokens_out, "+
+			"AVG(latency_ms * 1.0) AS avg_latency_ms", true).
+		Group("feature").
+		Order("feature").
+		Scan(&r.Features).Error; err != nil {
+		return r, err
+	}
+	// Features without calls still show their budget
+	for _, f := range Features {
+		if feature != "" && f != feature {
+			continue
+		}
+		if !slices.ContainsFunc(r.Features, func(u FeatureUsage) bool { return u.Feature == f }) {
+			r.Features = append(r.Features, FeatureUsage{Feature: f})
+		}
+	}
+	for i := range r.Features {
+		r.Features[i].BudgetPerDay = Budget(r.Features[i].Feature)
+		r.Features[i].UsedToday = UsedToday(db, r.Features[i].Feature)
+	}
+
+	if err := scope().
+		Select("CAST(created_at AS DATE) AS day, feature, COUNT(*) AS calls, " +
+			"COALESCE(SUM(tokens_in), 0) AS tokens_in, COALESCE(SUM(tokens_out), 0) AS tokens_out").
+		Group("CAST(created_at AS DATE), feature").
+		Order("day, feature").
+		Scan(&r.Daily).Error; err != nil {
+		return r, err
+	}
+
+	err := scope().
+		Select("user_id, COUNT(*) AS calls, COALESCE(SUM(tokens_in + tokens_out), 0) AS tokens").
+		Where("user_id IS NOT NULL").
+		Group("user_id").
+		Order("tokens DESC").
+		Limit(20).
+		Scan(&r.TopUsers).Error
+	return r, err
+}
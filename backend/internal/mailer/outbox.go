@@ -0,0 +1,182 @@
+package mailer
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/gomail.v2"
+	"gorm.io/gorm"
+)
+
+// Email is a templated email request
+type Email struct {
+	To       []string
+	Cc       []string
+	Template string
+	Lang     string // Empty uses MAIL_DEFAULT_LANG
+	Data     map[string]any
+}
+
+// Enqueue renders the email and stores it in the outbox; the worker sends it asynchronously
+func Enqueue(db *gorm.DB, email Email) (*model.EmailOutbox, error) {
+	if len(email.To) == 0 {
+		return nil, fmt.Errorf("recipient (to) list cannot be empty")
+	}
+	if email.Lang == "" {
+		email.Lang = DefaultLang()
+	}
+
+	subject, body, err := Render(email.Template, email.Lang, email.Data)
+	if err != nil {
+		return nil, fmt.Errorf("render email template %q: %w", email.Template, err)
+	}
+
+	data, _ := json.Marshal(email.Data)
+	outbox := model.EmailOutbox{
+		To:            strings.Join(email.To, ","),
+		Cc:            strings.Join(email.Cc, ","),
+		Subject:       subject,
+		Template:      email.Template,
+		Lang:          email.Lang,
+		Data:          data,
+		BodyHTML:      body,
+		Status:        model.EmailStatusPending,
+		MaxAttempts:   util.Getenv("MAIL_MAX_ATTEMPTS", 5),
+		NextAttemptAt: time.Now(),
+	}
+	if err := db.Create(&outbox).Error; err != nil {
+		return nil, err
+	}
+	return &outbox, nil
+}
+
+// Start runs the outbox worker that sends due emails with exponential backoff on failure
+func Start(db *gorm.DB) {
+	// Welcome email for new users
+	events.On("welcome_email", func(e events.UserRegistered) error {
+		if e.User.Email.String() == "" {
+			return nil
+		}
+		_, err := Enqueue(db, Email{
+			To:       []string{e.User.Email.String()},
+			Template: TemplateWelcome,
+			Data: map[string]any{
+				"Name": e.User.FirstName,
+				"Link": os.Getenv("APP_PUBLIC_URL") + "/",
+			},
+		})
+		return err
+	})
+
+	transport := NewTransport()
+	interval := time.Duration(util.Getenv("MAIL_POLL_INTERVAL_S", 5)) * time.Second
+
+	go func() {
+		// Emails claimed by a previous process that died mid-send
+		db.Model(&model.EmailOutbox{}).
+			Where("status = ? AND updated_at < ?", model.EmailStatusSending, time.Now().Add(-10*time.Minute)).
+			Update("status", model.EmailStatusPending)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			processDue(db, transport)
+		}
+	}()
+}
+
+func processDue(db *gorm.DB, transport Transport) {
+	var due []model.EmailOutbox
+	if err := db.Where("status = ? AND next_attempt_at <= ?", model.EmailStatusPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(20).
+		Find(&due).Error; err != nil {
+		return // Table may not be migrated yet
+	}
+
+	for _, email := range due {
+		// Claim the row so concurrent workers don't send it twice
+		res := db.Model(&model.EmailOutbox{}).
+			Where("id = ? AND status = ?", email.ID, model.EmailStatusPending).
+			Update("status", model.EmailStatusSending)
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+
+		err := transport.Send(compose(email))
+		email.Attempts++
+
+		if err == nil {
+			now := time.Now()
+			db.Model(&email).Updates(map[string]interface{}{
+				"status":     model.EmailStatusSent,
+				"attempts":   email.Attempts,
+				"sent_at":    now,
+				"last_error": "",
+			})
+			continue
+		}
+
+		status := model.EmailStatusPending
+		if email.Attempts >= email.MaxAttempts {
+			status = model.EmailStatusFailed
+			logrus.Errorf("Email %d to %s failed permanently after %d attempts: %v", email.ID, email.To, email.Attempts, err)
+		} else {
+			logrus.Warnf("Email %d to %s failed (attempt %d/%d): %v", email.ID, email.To, email.Attempts, email.MaxAttempts, err)
+		}
+		db.Model(&email).Updates(map[string]interface{}{
+			"status":          status,
+			"attempts":        email.Attempts,
+			"next_attempt_at": time.Now().Add(Backoff(email.Attempts)),
+			"last_error":      err.Error(),
+		})
+	}
+}
+
+// Backoff returns the delay before the next attempt: 30s, 1m, 2m, 4m ... capped at 1h
+func Backoff(attempts int) time.Duration {
+	d := time.Duration(float64(30*time.Second) * math.Pow(2, float64(attempts-1)))
+	if d > time.Hour || d <= 0 {
+		return time.Hour
+	}
+	return d
+}
+
+// Retry puts a failed email back in the queue for immediate sending
+func Retry(db *gorm.DB, id uint) error {
+	res := db.Model(&model.EmailOutbox{}).
+		Where("id = ? AND status = ?", id, model.EmailStatusFailed).
+		Updates(map[string]interface{}{
+			"status":          model.EmailStatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("email %d is not in failed state", id)
+	}
+	return nil
+}
+
+func compose(email model.EmailOutbox) *gomail.Message {
+	m := gomail.NewMessage()
+	m.SetHeader("From", appName()+" <"+os.Getenv("CONFIG_SMTP_SENDER")+">")
+	m.SetHeader("To", strings.Split(email.To, ",")...)
+	if email.Cc != "" {
+		m.SetHeader("Cc", strings.Split(email.Cc, ",")...)
+	}
+	m.SetHeader("Subject", email.Subject)
+	m.SetHeader("X-Outbox-ID", fmt.Sprint(email.ID))
+	m.SetBody("text/html", email.BodyHTML)
+	return m
+}
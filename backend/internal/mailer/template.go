@@ -0,0 +1,132 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// Template names
+const (
+	TemplateNotification = "notification"
+	TemplateWelcome      = "welcome"
+)
+
+// Supported languages, the first one is the fallback
+var Languages = []string{"id", "en"}
+
+// SampleData is used by the admin preview for each template
+var SampleData = map[string]map[string]any{
+	TemplateNotification: {
+		"Name":  "Budi",
+		"Title": "Harga turun: Panci Presto 24cm",
+		"Body":  "Panci Presto 24cm sekarang Rp189.000 (sebelumnya Rp249.000 saat kamu simpan).",
+		"Link":  "/products/1",
+	},
+	TemplateWelcome: {
+		"Name": "Budi",
+		"Link": "/",
+	},
+}
+
+var (
+	templates     map[string]*template.Template // key: name.lang
+	templatesOnce sync.Once
+	templatesErr  error
+)
+
+// view is what every template receives
+type view struct {
+	AppName string
+	Lang    string
+	Data    map[string]any
+}
+
+func loadTemplates() {
+	templates = map[string]*template.Template{}
+	base, err := template.ParseFS(templateFS, "templates/layout.html")
+	if err != nil {
+		templatesErr = err
+		return
+	}
+
+	files, _ := fs.Glob(templateFS, "templates/*.*.html")
+	for _, file := range files {
+		key := strings.TrimSuffix(strings.TrimPrefix(file, "templates/"), ".html")
+		t, err := template.Must(base.Clone()).ParseFS(templateFS, file)
+		if err != nil {
+			templatesErr = fmt.Errorf("parse %s: %w", file, err)
+			return
+		}
+		templates[key] = t
+	}
+}
+
+// TemplateNames returns the available template names
+func TemplateNames() []string {
+	templatesOnce.Do(loadTemplates)
+	seen := map[string]bool{}
+	names := []string{}
+	for key := range templates {
+		name := strings.SplitN(key, ".", 2)[0]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Render executes a named template in the given language and returns the subject and HTML body.
+// Unknown languages fall back to the default language.
+func Render(name, lang string, data map[string]any) (subject, body string, err error) {
+	templatesOnce.Do(loadTemplates)
+	if templatesErr != nil {
+		return "", "", templatesErr
+	}
+
+	t, ok := templates[name+"."+lang]
+	if !ok {
+		lang = Languages[0]
+		if t, ok = templates[name+"."+lang]; !ok {
+			return "", "", fmt.Errorf("email template %q not found", name)
+		}
+	}
+
+	v := view{AppName: appName(), Lang: lang, Data: data}
+
+	var subj, out bytes.Buffer
+	if err := t.ExecuteTemplate(&subj, "subject", v); err != nil {
+		return "", "", err
+	}
+	if err := t.ExecuteTemplate(&out, "layout", v); err != nil {
+		return "", "", err
+	}
+	// Subject is plain text, undo the HTML escaping
+	return strings.TrimSpace(html.UnescapeString(subj.String())), out.String(), nil
+}
+
+// DefaultLang returns the language used when the caller doesn't specify one
+func DefaultLang() string {
+	return util.Getenv("MAIL_DEFAULT_LANG", Languages[0])
+}
+
+func appName() string {
+	if name := os.Getenv("APP_NAME"); name != "" {
+		return name
+	}
+	return "Lucky Goods Store"
+}
@@ -0,0 +1,84 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"gopkg.in/gomail.v2"
+)
+
+// Transport modes, selected with MAIL_MODE
+const (
+	ModeSMTP    = "smtp"    // Real SMTP server from CONFIG_SMTP_* (default in release)
+	ModeFile    = "file"    // Write .eml files to MAIL_DEV_DIR (default in development)
+	ModeMailHog = "mailhog" // Local SMTP sink without auth, MAIL_SMTP_SINK (default localhost:1025)
+)
+
+// Transport delivers a composed message
+type Transport interface {
+	Send(m *gomail.Message) error
+}
+
+// NewTransport returns the transport configured by MAIL_MODE
+func NewTransport() Transport {
+	defaultMode := ModeSMTP
+	if util.IsDevMode() {
+		defaultMode = ModeFile
+	}
+
+	switch util.Getenv("MAIL_MODE", defaultMode) {
+	case ModeFile:
+		return fileTransport{dir: util.Getenv("MAIL_DEV_DIR", filepath.Join(os.Getenv("APP_DIR"), "tmp", "mail"))}
+	case ModeMailHog:
+		host, portStr, _ := strings.Cut(util.Getenv("MAIL_SMTP_SINK", "localhost:1025"), ":")
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			port = 1025
+		}
+		return smtpTransport{dialer: &gomail.Dialer{Host: host, Port: port}}
+	default:
+		port, err := strconv.Atoi(os.Getenv("CONFIG_SMTP_PORT"))
+		if err != nil {
+			port = 587
+		}
+		return smtpTransport{dialer: gomail.NewDialer(
+			os.Getenv("CONFIG_SMTP_HOST"),
+			port,
+			os.Getenv("CONFIG_AUTH_EMAIL"),
+			os.Getenv("CONFIG_AUTH_PASSWORD"),
+		)}
+	}
+}
+
+type smtpTransport struct {
+	dialer *gomail.Dialer
+}
+
+func (t smtpTransport) Send(m *gomail.Message) error {
+	return t.dialer.DialAndSend(m)
+}
+
+// fileTransport writes each message as an .eml file that any mail client can open
+type fileTransport struct {
+	dir string
+}
+
+func (t fileTransport) Send(m *gomail.Message) error {
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102_150405.000000"), util.GenerateRandomStringLowerCase(6))
+	f, err := os.Create(filepath.Join(t.dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = m.WriteTo(f)
+	return err
+}
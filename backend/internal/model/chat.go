@@ -4,24 +4,33 @@ import (
 	"database/sql"
 	"time"
 
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
-// Chat represents a one-to-one conversation between two users
+// Chat is a conversation between its participants: either two users, or a buyer and the team of the
+// shop the chat is addressed to. User1ID/User2ID keep the pair that started it (the starter and the
+// user or shop owner they contacted); access is decided by ChatParticipant.
 type Chat struct {
-	ID        uint           `gorm:"primaryKey;column:id" json:"id"`
-	User1ID   uint           `gorm:"column:user1_id;not null;index:idx_chat_users" json:"user1_id"`
-	User2ID   uint           `gorm:"column:user2_id;not null;index:idx_chat_users" json:"user2_id"`
-	ProductID sql.NullInt64  `gorm:"column:product_id;index" json:"product_id,omitempty"` // Optional: chat started from a product
-	CreatedAt time.Time      `gorm:"column:created_at" json:"created_at"`
-	UpdatedAt time.Time      `gorm:"column:updated_at" json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	ID              uint           `gorm:"primaryKey;column:id" json:"id"`
+	User1ID         uint           `gorm:"column:user1_id;not null;index:idx_chat_users" json:"user1_id"`
+	User2ID         uint           `gorm:"column:user2_id;not null;index:idx_chat_users" json:"user2_id"`
+	ProductID       sql.NullInt64  `gorm:"column:product_id;index" json:"product_id,omitempty"`   // Optional: chat started from a product
+	ShopID          *uint          `gorm:"column:shop_id;index" json:"shop_id,omitempty"`         // Set when addressed to a shop inbox
+	AssignedTo      *uint          `gorm:"column:assigned_to;index" json:"assigned_to,omitempty"` // Shop team member handling the chat
+	LegalHoldAt     *time.Time     `gorm:"column:legal_hold_at" json:"legal_hold_at,omitempty"`   // Exempt from the retention policy while set
+	LegalHoldReason string         `gorm:"column:legal_hold_reason;size:500" json:"legal_hold_reason,omitempty"`
+	CreatedAt       time.Time      `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       time.Time      `gorm:"column:updated_at" json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
 	// Relations
-	User1    User      `gorm:"foreignKey:User1ID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user1,omitempty"`
-	User2    User      `gorm:"foreignKey:User2ID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user2,omitempty"`
-	Product  *Product  `gorm:"foreignKey:ProductID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"product,omitempty"`
-	Messages []Message `gorm:"foreignKey:ChatID" json:"messages,omitempty"`
+	User1        User              `gorm:"foreignKey:User1ID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user1,omitempty"`
+	User2        User              `gorm:"foreignKey:User2ID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user2,omitempty"`
+	Product      *Product          `gorm:"foreignKey:ProductID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"product,omitempty"`
+	Shop         *Shop             `gorm:"foreignKey:ShopID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"shop,omitempty"`
+	Participants []ChatParticipant `gorm:"foreignKey:ChatID" json:"participants,omitempty"`
+	Messages     []Message         `gorm:"foreignKey:ChatID" json:"messages,omitempty"`
 
 	// Virtual fields (not in DB, populated by queries)
 	LastMessage *Message `gorm:"-" json:"last_message,omitempty"`
@@ -39,23 +48,29 @@ type Message struct {
 	ChatID           uint           `gorm:"column:chat_id;not null;index" json:"chat_id"`
 	SenderID         uint           `gorm:"column:sender_id;not null;index" json:"sender_id"`
 	ReceiverID       uint           `gorm:"column:receiver_id;not null;index" json:"receiver_id"`
+	Kind             string         `gorm:"column:kind;size:20;default:'text'" json:"kind"` // text, product_card, offer, system
 	Content          string         `gorm:"column:content;type:text;not null" json:"content"`
+	Payload          datatypes.JSON `gorm:"column:payload" json:"payload,omitempty"` // Structured data for non-text kinds
 	AttachmentURL    string         `gorm:"column:attachment_url;size:500" json:"attachment_url,omitempty"`
-	AttachmentType   string         `gorm:"column:attachment_type;size:50" json:"attachment_type,omitempty"` // image, file, etc
+	AttachmentType   string         `gorm:"column:attachment_type;size:50" json:"attachment_type,omitempty"` // image, pdf, video
+	AttachmentID     *uint          `gorm:"column:attachment_id;index" json:"attachment_id,omitempty"`
 	ReplyToMessageID sql.NullInt64  `gorm:"column:reply_to_message_id;index" json:"reply_to_message_id,omitempty"`
 	IsEdited         bool           `gorm:"column:is_edited;default:false" json:"is_edited"`
 	IsDeleted        bool           `gorm:"column:is_deleted;default:false" json:"is_deleted"`
+	IsFlagged        bool           `gorm:"column:is_flagged" json:"is_flagged"` // Matched the content filter and queued for review
 	ReceivedAt       *time.Time     `gorm:"column:received_at" json:"received_at,omitempty"`
 	ReadAt           *time.Time     `gorm:"column:read_at" json:"read_at,omitempty"`
+	RedactedAt       *time.Time     `gorm:"column:redacted_at;index" json:"redacted_at,omitempty"` // Content removed by the retention policy
 	CreatedAt        time.Time      `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt        time.Time      `gorm:"column:updated_at" json:"updated_at"`
 	DeletedAt        gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
 	// Relations
-	Chat           Chat     `gorm:"foreignKey:ChatID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"chat,omitempty"`
-	Sender         User     `gorm:"foreignKey:SenderID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"sender,omitempty"`
-	Receiver       User     `gorm:"foreignKey:ReceiverID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"receiver,omitempty"`
-	ReplyToMessage *Message `gorm:"foreignKey:ReplyToMessageID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"reply_to,omitempty"`
+	Chat           Chat            `gorm:"foreignKey:ChatID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"chat,omitempty"`
+	Sender         User            `gorm:"foreignKey:SenderID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"sender,omitempty"`
+	Receiver       User            `gorm:"foreignKey:ReceiverID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"receiver,omitempty"`
+	ReplyToMessage *Message        `gorm:"foreignKey:ReplyToMessageID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"reply_to,omitempty"`
+	Attachment     *ChatAttachment `gorm:"foreignKey:AttachmentID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"attachment,omitempty"`
 
 	// Virtual fields
 	CanEdit   bool `gorm:"-" json:"can_edit"`   // Can be edited (within 7 mins and not read)
@@ -74,8 +89,8 @@ func (m *Message) AfterFind(tx *gorm.DB) error {
 	withinTimeLimit := timeSinceSent <= 7*time.Minute
 	notRead := m.ReadAt == nil
 
-	m.CanEdit = withinTimeLimit && notRead && !m.IsDeleted
-	m.CanDelete = withinTimeLimit && notRead && !m.IsDeleted
+	m.CanEdit = withinTimeLimit && notRead && !m.IsDeleted && (m.Kind == "" || m.Kind == MessageText)
+	m.CanDelete = withinTimeLimit && notRead && !m.IsDeleted && m.Kind != MessageOffer && m.Kind != MessageSystem
 
 	return nil
 }
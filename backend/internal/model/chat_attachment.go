@@ -0,0 +1,69 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"gorm.io/gorm"
+)
+
+// Chat attachment kinds
+const (
+	AttachmentImage = "image"
+	AttachmentPDF   = "pdf"
+	AttachmentVideo = "video"
+)
+
+// ChatAttachment is a file uploaded into a chat. Files are stored outside any public directory
+// and are only served to the chat's participants.
+type ChatAttachment struct {
+	ID            uint      `gorm:"primaryKey;column:id" json:"id"`
+	ChatID        uint      `gorm:"column:chat_id;not null;index" json:"chat_id"`
+	UploaderID    uint      `gorm:"column:uploader_id;not null;index" json:"uploader_id"`
+	MessageID     *uint     `gorm:"column:message_id;index" json:"message_id,omitempty"` // Set once sent in a message
+	Kind          string    `gorm:"column:kind;size:16;not null" json:"kind"`
+	MimeType      string    `gorm:"column:mime_type;size:100;not null" json:"mime_type"` // Sniffed from content, not client supplied
+	FileName      string    `gorm:"column:file_name;size:255" json:"file_name"`
+	Size          int64     `gorm:"column:size" json:"size"`
+	Width         int       `gorm:"column:width" json:"width,omitempty"`
+	Height        int       `gorm:"column:height" json:"height,omitempty"`
+	StoragePath   string    `gorm:"column:storage_path;size:500;not null" json:"-"`
+	ThumbnailPath string    `gorm:"column:thumbnail_path;size:500" json:"-"`
+	CreatedAt     time.Time `gorm:"column:created_at" json:"created_at"`
+
+	// Relations
+	Chat Chat `gorm:"foreignKey:ChatID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+
+	// Virtual fields
+	URL          string `gorm:"-" json:"url"`
+	ThumbnailURL string `gorm:"-" json:"thumbnail_url,omitempty"`
+}
+
+func (ChatAttachment) TableName() string {
+	return "chat_attachments"
+}
+
+// DownloadPath is the participant-only download endpoint for the attachment
+func (a ChatAttachment) DownloadPath() string {
+	return fmt.Sprintf("%s/chat-attachments/%d", util.GetPathOnly(util.Getenv("VITE_BACKEND", "/api")), a.ID)
+}
+
+// AfterFind hook to populate virtual fields
+func (a *ChatAttachment) AfterFind(tx *gorm.DB) error {
+	a.setURLs()
+	return nil
+}
+
+// AfterCreate hook to populate virtual fields
+func (a *ChatAttachment) AfterCreate(tx *gorm.DB) error {
+	a.setURLs()
+	return nil
+}
+
+func (a *ChatAttachment) setURLs() {
+	a.URL = a.DownloadPath()
+	if a.ThumbnailPath != "" {
+		a.ThumbnailURL = a.URL + "/thumbnail"
+	}
+}
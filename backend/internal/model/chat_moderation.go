@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+)
+
+// Chat report statuses
+const (
+	ReportOpen      = "open"
+	ReportDismissed = "dismissed"
+	ReportActioned  = "actioned"
+)
+
+// Chat report reasons; ReportReasonFilter is used for messages flagged automatically
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonAbuse         = "abuse"
+	ReportReasonScam          = "scam"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonOther         = "other"
+	ReportReasonFilter        = "filter"
+)
+
+// UserBlock stops BlockedID from opening chats with, messaging, or seeing the presence of BlockerID
+type UserBlock struct {
+	ID        uint      `gorm:"primaryKey;column:id" json:"id"`
+	BlockerID uint      `gorm:"column:blocker_id;not null;uniqueIndex:idx_user_block_pair" json:"blocker_id"`
+	BlockedID uint      `gorm:"column:blocked_id;not null;uniqueIndex:idx_user_block_pair;index" json:"blocked_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+
+	// Relations
+	Blocked User `gorm:"foreignKey:BlockedID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"blocked,omitempty"`
+}
+
+func (UserBlock) TableName() string {
+	return "user_blocks"
+}
+
+// ChatReport is an entry in the admin review queue, raised by a user or by the content filter
+type ChatReport struct {
+	ID             uint       `gorm:"primaryKey;column:id" json:"id"`
+	ReporterID     *uint      `gorm:"column:reporter_id;index" json:"reporter_id,omitempty"` // Nil when raised by the content filter
+	ReportedUserID uint       `gorm:"column:reported_user_id;not null;index" json:"reported_user_id"`
+	ChatID         *uint      `gorm:"column:chat_id;index" json:"chat_id,omitempty"`
+	MessageID      *uint      `gorm:"column:message_id;index" json:"message_id,omitempty"`
+	MessageContent string     `gorm:"column:message_content;type:text" json:"message_content,omitempty"` // Snapshot, kept if the message is later deleted
+	Reason         string     `gorm:"column:reason;size:30;not null" json:"reason"`
+	Details        string     `gorm:"column:details;type:text" json:"details,omitempty"`
+	Status         string     `gorm:"column:status;size:20;not null;index" json:"status"`
+	Action         string     `gorm:"column:action;size:30" json:"action,omitempty"` // Taken on review: none, remove_message, suspend_user
+	ReviewNotes    string     `gorm:"column:review_notes;type:text" json:"review_notes,omitempty"`
+	ReviewedBy     *uint      `gorm:"column:reviewed_by" json:"reviewed_by,omitempty"`
+	ReviewedAt     *time.Time `gorm:"column:reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"column:updated_at" json:"updated_at"`
+
+	// Relations
+	Reporter     *User `gorm:"foreignKey:ReporterID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"reporter,omitempty"`
+	ReportedUser User  `gorm:"foreignKey:ReportedUserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"reported_user,omitempty"`
+}
+
+func (ChatReport) TableName() string {
+	return "chat_reports"
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"time"
+)
+
+// Message kinds
+const (
+	MessageText        = "text"
+	MessageProductCard = "product_card"
+	MessageOffer       = "offer"
+	MessageSystem      = "system"
+)
+
+// Chat offer statuses
+const (
+	OfferPending   = "pending"
+	OfferAccepted  = "accepted"
+	OfferRejected  = "rejected"
+	OfferCountered = "countered"
+	OfferExpired   = "expired"
+)
+
+// ProductCardPayload is a snapshot of a product shared in a chat
+type ProductCardPayload struct {
+	ProductID    uint    `json:"product_id"`
+	ShopID       uint    `json:"shop_id"`
+	Name         string  `json:"name"`
+	Slug         string  `json:"slug"`
+	ImageURL     string  `json:"image_url"`
+	Price        float64 `json:"price"`
+	SlashedPrice float64 `json:"slashed_price,omitempty"`
+	Stock        int     `json:"stock"`
+}
+
+// OfferPayload is carried by offer messages; Status is kept in sync with the ChatOffer
+type OfferPayload struct {
+	OfferID    uint               `json:"offer_id"`
+	Product    ProductCardPayload `json:"product"`
+	Price      float64            `json:"price"`
+	Note       string             `json:"note,omitempty"`
+	Status     string             `json:"status"`
+	ProposedBy uint               `json:"proposed_by"`
+	CounterOf  *uint              `json:"counter_of,omitempty"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+}
+
+// SystemPayload describes an automatic notice posted into a chat
+type SystemPayload struct {
+	Event   string `json:"event"` // offer.accepted, offer.rejected, auto_reply.greeting, auto_reply.away, chat.assigned
+	OfferID uint   `json:"offer_id,omitempty"`
+	UserID  *uint  `json:"user_id,omitempty"` // chat.assigned: the new assignee, nil when unassigned
+}
+
+// ProductCard builds the chat card snapshot of a product
+func (p Product) ProductCard() ProductCardPayload {
+	return ProductCardPayload{
+		ProductID:    p.ID,
+		ShopID:       p.ShopID,
+		Name:         p.Name,
+		Slug:         p.Slug,
+		ImageURL:     p.ImageURL,
+		Price:        p.Price,
+		SlashedPrice: p.SlashedPrice,
+		Stock:        p.Stock,
+	}
+}
+
+// ChatOffer is a negotiated price ("nego") proposed in a chat. The participant who did not
+// propose it can accept, reject or counter; a counter creates a new offer linked by CounterOfID.
+type ChatOffer struct {
+	ID          uint       `gorm:"primaryKey;column:id" json:"id"`
+	ChatID      uint       `gorm:"column:chat_id;not null;index" json:"chat_id"`
+	MessageID   uint       `gorm:"column:message_id;index" json:"message_id"`
+	ProductID   uint       `gorm:"column:product_id;not null;index" json:"product_id"`
+	BuyerID     uint       `gorm:"column:buyer_id;not null;index" json:"buyer_id"`
+	SellerID    uint       `gorm:"column:seller_id;not null;index" json:"seller_id"`
+	ProposedBy  uint       `gorm:"column:proposed_by;not null" json:"proposed_by"`
+	Price       float64    `gorm:"column:price;not null" json:"price"`
+	Note        string     `gorm:"column:note;size:500" json:"note,omitempty"`
+	Status      string     `gorm:"column:status;size:20;not null;index" json:"status"`
+	CounterOfID *uint      `gorm:"column:counter_of_id;index" json:"counter_of_id,omitempty"`
+	ExpiresAt   time.Time  `gorm:"column:expires_at" json:"expires_at"`
+	RespondedAt *time.Time `gorm:"column:responded_at" json:"responded_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"column:updated_at" json:"updated_at"`
+
+	// Relations
+	Chat    Chat    `gorm:"foreignKey:ChatID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Product Product `gorm:"foreignKey:ProductID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (ChatOffer) TableName() string {
+	return "chat_offers"
+}
+
+// Responder returns the participant expected to answer the offer
+func (o ChatOffer) Responder() uint {
+	if o.ProposedBy == o.BuyerID {
+		return o.SellerID
+	}
+	return o.BuyerID
+}
+
+// IsExpired reports whether a pending offer has passed its deadline
+func (o ChatOffer) IsExpired() bool {
+	return o.Status == OfferPending && time.Now().After(o.ExpiresAt)
+}
+
+// SpecialPrice is a time-limited price granted to one user for one product by an accepted offer.
+// The token is only meaningful together with the buyer's own session.
+type SpecialPrice struct {
+	ID        uint      `gorm:"primaryKey;column:id" json:"id"`
+	Token     string    `gorm:"column:token;size:64;uniqueIndex;not null" json:"token"`
+	UserID    uint      `gorm:"column:user_id;not null;index" json:"user_id"`
+	ProductID uint      `gorm:"column:product_id;not null;index" json:"product_id"`
+	OfferID   uint      `gorm:"column:offer_id;not null;uniqueIndex" json:"offer_id"`
+	Price     float64   `gorm:"column:price;not null" json:"price"`
+	ExpiresAt time.Time `gorm:"column:expires_at;index" json:"expires_at"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+
+	// Relations
+	Product Product `gorm:"foreignKey:ProductID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"product,omitempty"`
+}
+
+func (SpecialPrice) TableName() string {
+	return "special_prices"
+}
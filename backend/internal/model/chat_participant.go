@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+)
+
+// Chat participant roles. Chats addressed to a shop have one buyer plus the shop's owner and staff;
+// direct chats between two users have two members.
+const (
+	ChatRoleBuyer  = "buyer"
+	ChatRoleOwner  = "owner"
+	ChatRoleStaff  = "staff"
+	ChatRoleMember = "member"
+)
+
+// ChatParticipant grants a user access to a chat and tracks how far they have read
+type ChatParticipant struct {
+	ID                uint       `gorm:"primaryKey;column:id" json:"id"`
+	ChatID            uint       `gorm:"column:chat_id;not null;uniqueIndex:idx_chat_participant" json:"chat_id"`
+	UserID            uint       `gorm:"column:user_id;not null;uniqueIndex:idx_chat_participant;index" json:"user_id"`
+	Role              string     `gorm:"column:role;size:20;not null" json:"role"`
+	LastReadMessageID uint       `gorm:"column:last_read_message_id;default:0" json:"last_read_message_id"`
+	LastReadAt        *time.Time `gorm:"column:last_read_at" json:"last_read_at,omitempty"`
+	CreatedAt         time.Time  `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt         time.Time  `gorm:"column:updated_at" json:"updated_at"`
+
+	// Relations
+	Chat Chat `gorm:"foreignKey:ChatID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user,omitempty"`
+}
+
+func (ChatParticipant) TableName() string {
+	return "chat_participants"
+}
+
+// IsShopTeam reports whether the participant answers on behalf of the shop
+func (p ChatParticipant) IsShopTeam() bool {
+	return p.Role == ChatRoleOwner || p.Role == ChatRoleStaff
+}
+
+// ShopStaff lets a user other than the owner work in a shop's chat inbox
+type ShopStaff struct {
+	ID        uint      `gorm:"primaryKey;column:id" json:"id"`
+	ShopID    uint      `gorm:"column:shop_id;not null;uniqueIndex:idx_shop_staff" json:"shop_id"`
+	UserID    uint      `gorm:"column:user_id;not null;uniqueIndex:idx_shop_staff;index" json:"user_id"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+
+	// Relations
+	Shop Shop `gorm:"foreignKey:ShopID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user,omitempty"`
+}
+
+func (ShopStaff) TableName() string {
+	return "shop_staff"
+}
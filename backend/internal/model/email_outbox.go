@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Email outbox statuses
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSending = "sending"
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "failed"
+)
+
+// EmailOutbox is a rendered email waiting to be sent by the mailer worker
+// Failed sends are retried with exponential backoff until MaxAttempts is reached
+type EmailOutbox struct {
+	ID            uint           `gorm:"primaryKey;column:id" json:"id"`
+	To            string         `gorm:"column:to_addr;type:text;not null" json:"to"` // Comma separated
+	Cc            string         `gorm:"column:cc_addr;type:text" json:"cc,omitempty"`
+	Subject       string         `gorm:"column:subject;size:255;not null" json:"subject"`
+	Template      string         `gorm:"column:template;size:100;index" json:"template"`
+	Lang          string         `gorm:"column:lang;size:5" json:"lang"`
+	Data          datatypes.JSON `gorm:"column:data;type:json" json:"data,omitempty"`
+	BodyHTML      string         `gorm:"column:body_html;type:text" json:"body_html"`
+	Status        string         `gorm:"column:status;size:16;not null;index:idx_email_outbox_due" json:"status"`
+	Attempts      int            `gorm:"column:attempts;default:0" json:"attempts"`
+	MaxAttempts   int            `gorm:"column:max_attempts;default:5" json:"max_attempts"`
+	NextAttemptAt time.Time      `gorm:"column:next_attempt_at;index:idx_email_outbox_due" json:"next_attempt_at"`
+	LastError     string         `gorm:"column:last_error;type:text" json:"last_error,omitempty"`
+	SentAt        *time.Time     `gorm:"column:sent_at" json:"sent_at,omitempty"`
+	CreatedAt     time.Time      `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (EmailOutbox) TableName() string {
+	return "email_outbox"
+}
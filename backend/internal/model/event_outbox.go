@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Event outbox statuses
+const (
+	EventStatusPending    = "pending"
+	EventStatusProcessing = "processing"
+	EventStatusProcessed  = "processed"
+	EventStatusFailed     = "failed"
+)
+
+// EventOutbox stores domain events written in the same transaction as the change that caused them
+// A relay goroutine dispatches them to in-process subscribers (at-least-once)
+type EventOutbox struct {
+	ID            uint           `gorm:"primaryKey;column:id" json:"id"`
+	EventID       string         `gorm:"column:event_id;size:36;uniqueIndex;not null" json:"event_id"`
+	Type          string         `gorm:"column:type;size:64;index;not null" json:"type"`
+	AggregateID   string         `gorm:"column:aggregate_id;size:64;index" json:"aggregate_id"`
+	Payload       datatypes.JSON `gorm:"column:payload;type:json" json:"payload"`
+	Status        string         `gorm:"column:status;size:16;not null;index:idx_event_outbox_due" json:"status"`
+	Attempts      int            `gorm:"column:attempts;default:0" json:"attempts"`
+	NextAttemptAt time.Time      `gorm:"column:next_attempt_at;index:idx_event_outbox_due" json:"next_attempt_at"`
+	LockedUntil   *time.Time     `gorm:"column:locked_until" json:"locked_until,omitempty"`           // Claim of the relay instance dispatching the event
+	DeliveredTo   datatypes.JSON `gorm:"column:delivered_to;type:json" json:"delivered_to,omitempty"` // Subscribers that already handled the event
+	LastError     string         `gorm:"column:last_error;type:text" json:"last_error,omitempty"`
+	ProcessedAt   *time.Time     `gorm:"column:processed_at" json:"processed_at,omitempty"`
+	CreatedAt     time.Time      `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (EventOutbox) TableName() string {
+	return "event_outbox"
+}
@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+)
+
+// LLM operations recorded in LLMUsage
+const (
+	LLMOpComplete = "complete"
+	LLMOpChat     = "chat"
+	LLMOpEmbed    = "embed"
+)
+
+// LLMUsage records one call to a language model, successful or not
+type LLMUsage struct {
+	ID        uint      `gorm:"primaryKey;column:id" json:"id"`
+	Feature   string    `gorm:"column:feature;size:64;not null;index:idx_llm_usage_feature_time" json:"feature"`
+	Operation string    `gorm:"column:operation;size:16;not null" json:"operation"`
+	Provider  string    `gorm:"column:provider;size:32" json:"provider"`
+	Model     string    `gorm:"column:model;size:100" json:"model"`
+	UserID    *uint     `gorm:"column:user_id;index" json:"user_id,omitempty"` // nil for background jobs
+	TokensIn  int       `gorm:"column:tokens_in;default:0" json:"tokens_in"`
+	TokensOut int       `gorm:"column:tokens_out;default:0" json:"tokens_out"`
+	LatencyMs int64     `gorm:"column:latency_ms;default:0" json:"latency_ms"`
+	Success   bool      `gorm:"column:success;default:false" json:"success"`
+	Error     string    `gorm:"column:error;size:500" json:"error,omitempty"`
+	CreatedAt time.Time `gorm:"column:created_at;index:idx_llm_usage_feature_time" json:"created_at"`
+}
+
+func (LLMUsage) TableName() string {
+	return "llm_usages"
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Notification types
+const (
+	NotificationWishlistPriceDrop   = "wishlist_price_drop"
+	NotificationWishlistBackInStock = "wishlist_back_in_stock"
+	NotificationChatMessage         = "chat_message"
+	NotificationSystem              = "system"
+)
+
+// Notification delivery channels
+const (
+	ChannelInApp = "in_app"
+	ChannelEmail = "email"
+)
+
+// Notification is a persisted in-app notification for a user
+// Delivered in real time over websocket when the user is online, kept for later otherwise
+type Notification struct {
+	ID        uint           `gorm:"primaryKey;column:id" json:"id"`
+	UserID    uint           `gorm:"column:user_id;not null;index:idx_notification_user_read" json:"user_id"`
+	Type      string         `gorm:"column:type;size:50;not null;index" json:"type"`
+	Title     string         `gorm:"column:title;size:255;not null" json:"title"`
+	Body      string         `gorm:"column:body;type:text" json:"body"`
+	Link      string         `gorm:"column:link;size:500" json:"link,omitempty"`
+	Payload   datatypes.JSON `gorm:"column:payload;type:json" json:"payload,omitempty"`
+	ReadAt    *time.Time     `gorm:"column:read_at;index:idx_notification_user_read" json:"read_at"`
+	CreatedAt time.Time      `gorm:"column:created_at;index" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"column:updated_at" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// NotificationPreference stores which channels a user wants for a notification type
+// Missing rows fall back to DefaultNotificationPreference
+type NotificationPreference struct {
+	ID        uint      `gorm:"primaryKey;column:id" json:"id"`
+	UserID    uint      `gorm:"column:user_id;not null;uniqueIndex:idx_user_notification_type" json:"user_id"`
+	Type      string    `gorm:"column:type;size:50;not null;uniqueIndex:idx_user_notification_type" json:"type"`
+	InApp     bool      `gorm:"column:in_app" json:"in_app"`
+	Email     bool      `gorm:"column:email" json:"email"`
+	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
+
+// NotificationTypes lists the notification types users can configure, in display order
+var NotificationTypes = []string{
+	NotificationWishlistPriceDrop,
+	NotificationWishlistBackInStock,
+	NotificationChatMessage,
+	NotificationSystem,
+}
+
+// DefaultNotificationPreference returns the channels used when the user hasn't configured a type
+func DefaultNotificationPreference(userID uint, notificationType string) NotificationPreference {
+	pref := NotificationPreference{UserID: userID, Type: notificationType, InApp: true}
+	switch notificationType {
+	case NotificationWishlistPriceDrop, NotificationWishlistBackInStock, NotificationSystem:
+		pref.Email = true
+	}
+	return pref
+}
@@ -99,6 +99,8 @@ type Product struct {
 	Slug          string         `gorm:"column:slug;size:255;unique;not null;index" json:"slug"`
 	Subtitle      string         `gorm:"column:subtitle;size:255" json:"subtitle" ui:"creatable;visible;editable"`
 	Description   string         `gorm:"column:description;type:text" json:"description" ui:"creatable;visible;editable"`
+	SEOTitle      string         `gorm:"column:seo_title;size:100" json:"seo_title" ui:"creatable;editable"`
+	SEODesc       string         `gorm:"column:seo_description;size:255" json:"seo_description" ui:"creatable;editable"`
 	ImageURL      string         `gorm:"column:image_url;size:500;not null" json:"image_url" ui:"creatable;visible;editable"`
 	Price         float64        `gorm:"column:price;not null" json:"price" ui:"creatable;visible;editable;filterable;sortable"`
 	SlashedPrice  float64        `gorm:"column:slashed_price" json:"slashed_price" ui:"creatable;visible;editable"`
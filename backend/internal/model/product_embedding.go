@@ -0,0 +1,23 @@
+package model
+
+import (
+	"time"
+)
+
+// ProductEmbedding is the vector of a product's name, category and description used by semantic search.
+// On Postgres with pgvector the vector is also kept in an untyped "embedding vector" column, indexed per
+// dimension with a partial HNSW index once vectors of that size are stored.
+type ProductEmbedding struct {
+	ID          uint      `gorm:"primaryKey;column:id" json:"id"`
+	ProductID   uint      `gorm:"column:product_id;not null;uniqueIndex" json:"product_id"`
+	Model       string    `gorm:"column:model;size:150;not null;index" json:"model"` // provider/model; vectors of other models are ignored
+	Dims        int       `gorm:"column:dims;not null" json:"dims"`
+	Vector      []byte    `gorm:"column:vector" json:"-"`                          // Little-endian float32s
+	ContentHash string    `gorm:"column:content_hash;size:64" json:"content_hash"` // Skips re-embedding when the text did not change
+	CreatedAt   time.Time `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at" json:"updated_at"`
+}
+
+func (ProductEmbedding) TableName() string {
+	return "product_embeddings"
+}
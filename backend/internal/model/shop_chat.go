@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// OperatingHours is one opening window of a shop. Close before Open means the window runs past midnight.
+type OperatingHours struct {
+	Day   time.Weekday `json:"day"`   // 0 = Sunday
+	Open  string       `json:"open"`  // HH:MM in the shop's timezone
+	Close string       `json:"close"` // HH:MM in the shop's timezone
+}
+
+// ShopChatSettings configures the automatic replies a shop sends in chat. Messages may use the
+// {buyer_name}, {product_name} and {shop_name} placeholders.
+type ShopChatSettings struct {
+	ID              uint                                `gorm:"primaryKey;column:id" json:"id"`
+	ShopID          uint                                `gorm:"column:shop_id;not null;uniqueIndex" json:"shop_id"`
+	GreetingEnabled bool                                `gorm:"column:greeting_enabled" json:"greeting_enabled"`
+	GreetingMessage string                              `gorm:"column:greeting_message;type:text" json:"greeting_message"`
+	AwayEnabled     bool                                `gorm:"column:away_enabled" json:"away_enabled"`
+	AwayMessage     string                              `gorm:"column:away_message;type:text" json:"away_message"`
+	Timezone        string                              `gorm:"column:timezone;size:64" json:"timezone"`
+	OperatingHours  datatypes.JSONSlice[OperatingHours] `gorm:"column:operating_hours" json:"operating_hours"`
+	CreatedAt       time.Time                           `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt       time.Time                           `gorm:"column:updated_at" json:"updated_at"`
+
+	// Relations
+	Shop Shop `gorm:"foreignKey:ShopID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (ShopChatSettings) TableName() string {
+	return "shop_chat_settings"
+}
+
+// QuickReply is a saved message template a seller can send in chat
+type QuickReply struct {
+	ID        uint           `gorm:"primaryKey;column:id" json:"id"`
+	ShopID    uint           `gorm:"column:shop_id;not null;index" json:"shop_id"`
+	Title     string         `gorm:"column:title;size:100;not null" json:"title"`
+	Content   string         `gorm:"column:content;type:text;not null" json:"content"`
+	Position  int            `gorm:"column:position;default:0" json:"position"`
+	CreatedAt time.Time      `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"column:updated_at" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	// Relations
+	Shop Shop `gorm:"foreignKey:ShopID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (QuickReply) TableName() string {
+	return "quick_replies"
+}
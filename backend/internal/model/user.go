@@ -31,6 +31,10 @@ type User struct {
 	UserRole           UserRole       `gorm:"foreignKey:RoleID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"user_role" `
 	Role               types.HTML     `gorm:"-" json:"role" ui:"visible;visibility;editable;filterable;sortable;selection:/options?data=role"`
 	HasShop            bool           `gorm:"-" json:"has_shop"` // Virtual field to check if user has shop
+	HideOnlineStatus   bool           `gorm:"column:hide_online_status" json:"hide_online_status"`
+	LastSeenAt         *time.Time     `gorm:"column:last_seen_at" json:"-"`    // Exposed as last_seen_at only through presence, which honours privacy
+	Online             *bool          `gorm:"-" json:"online,omitempty"`       // Virtual: set where presence is shown
+	LastSeen           *time.Time     `gorm:"-" json:"last_seen_at,omitempty"` // Virtual: set where presence is shown
 
 	CreatedAt time.Time `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt time.Time `gorm:"column:updated_at" json:"updated_at"`
@@ -91,6 +95,9 @@ func (m User) IgnoredColumn() []string {
 		"password",
 		"session",
 		"deleted_at",
+		"hide_online_status",
+		"online",
+		"last_seen_at",
 	}
 }
 func (m User) TableSettings(url string) map[string]any {
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending = "pending"
+	WebhookDeliverySending = "sending"
+	WebhookDeliverySuccess = "success"
+	WebhookDeliveryFailed  = "failed"
+)
+
+// WebhookEndpoint is a seller-registered URL that receives signed event payloads for their shop
+type WebhookEndpoint struct {
+	ID             uint                        `gorm:"primaryKey;column:id" json:"id"`
+	ShopID         uint                        `gorm:"column:shop_id;not null;index" json:"shop_id"`
+	URL            string                      `gorm:"column:url;size:500;not null" json:"url"`
+	Secret         string                      `gorm:"column:secret;size:100;not null" json:"-"` // HMAC-SHA256 signing key, only shown on create/rotate
+	EventTypes     datatypes.JSONSlice[string] `gorm:"column:event_types;type:json" json:"event_types"`
+	Description    string                      `gorm:"column:description;size:255" json:"description,omitempty"`
+	IsActive       bool                        `gorm:"column:is_active" json:"is_active"`
+	FailureCount   int                         `gorm:"column:failure_count;default:0" json:"failure_count"` // Consecutive failed attempts
+	DisabledAt     *time.Time                  `gorm:"column:disabled_at" json:"disabled_at,omitempty"`
+	DisabledReason string                      `gorm:"column:disabled_reason;size:255" json:"disabled_reason,omitempty"`
+	CreatedAt      time.Time                   `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt      time.Time                   `gorm:"column:updated_at" json:"updated_at"`
+	DeletedAt      gorm.DeletedAt              `gorm:"index" json:"deleted_at,omitempty"`
+
+	// Relations
+	Shop Shop `gorm:"foreignKey:ShopID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// Subscribes reports whether the endpoint wants the given event type
+func (w WebhookEndpoint) Subscribes(eventType string) bool {
+	return slices.Contains(w.EventTypes, eventType)
+}
+
+// WebhookDelivery is one event sent (or to be sent) to an endpoint, kept as the delivery log
+type WebhookDelivery struct {
+	ID            uint           `gorm:"primaryKey;column:id" json:"id"`
+	EndpointID    uint           `gorm:"column:endpoint_id;not null;index" json:"endpoint_id"`
+	EventID       string         `gorm:"column:event_id;size:36;index" json:"event_id"`
+	EventType     string         `gorm:"column:event_type;size:64" json:"event_type"`
+	AggregateID   string         `gorm:"column:aggregate_id;size:64;index" json:"aggregate_id"` // The event's aggregate, e.g. the chat of a message
+	Payload       datatypes.JSON `gorm:"column:payload;type:json" json:"payload"`
+	Status        string         `gorm:"column:status;size:16;not null;index:idx_webhook_delivery_due" json:"status"`
+	Attempts      int            `gorm:"column:attempts;default:0" json:"attempts"`
+	NextAttemptAt time.Time      `gorm:"column:next_attempt_at;index:idx_webhook_delivery_due" json:"next_attempt_at"`
+	ResponseCode  int            `gorm:"column:response_code" json:"response_code,omitempty"`
+	Error         string         `gorm:"column:error;type:text" json:"error,omitempty"`
+	DurationMs    int64          `gorm:"column:duration_ms" json:"duration_ms"`
+	RedeliveryOf  *uint          `gorm:"column:redelivery_of" json:"redelivery_of,omitempty"` // Set on manual redelivery
+	DeliveredAt   *time.Time     `gorm:"column:delivered_at" json:"delivered_at,omitempty"`
+	CreatedAt     time.Time      `gorm:"column:created_at;index" json:"created_at"`
+	UpdatedAt     time.Time      `gorm:"column:updated_at" json:"updated_at"`
+
+	// Relations
+	Endpoint WebhookEndpoint `gorm:"foreignKey:EndpointID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -9,19 +9,70 @@ import (
 // WishlistItem represents an item in the user's wishlist
 // Users can save products they're interested in for later
 type WishlistItem struct {
-	ID        uint           `gorm:"primaryKey;column:id" json:"id"`
-	UserID    uint           `gorm:"column:user_id;not null;uniqueIndex:idx_user_product" json:"user_id"`
-	ProductID uint           `gorm:"column:product_id;not null;uniqueIndex:idx_user_product" json:"product_id"`
-	Notes     string         `gorm:"column:notes;type:text" json:"notes,omitempty"`
+	ID        uint `gorm:"primaryKey;column:id" json:"id"`
+	UserID    uint `gorm:"column:user_id;not null;uniqueIndex:idx_user_product" json:"user_id"`
+	ProductID uint `gorm:"column:product_id;not null;uniqueIndex:idx_user_product" json:"product_id"`
+	// Optional: named collection the item is organized in (nil = default list)
+	CollectionID *uint  `gorm:"column:collection_id;index" json:"collection_id,omitempty"`
+	Notes        string `gorm:"column:notes;type:text" json:"notes,omitempty"`
+
+	// Alerts (opt-in per item)
+	NotifyPriceDrop   bool       `gorm:"column:notify_price_drop;default:false" json:"notify_price_drop"`
+	NotifyBackInStock bool       `gorm:"column:notify_back_in_stock;default:false" json:"notify_back_in_stock"`
+	SavedPrice        float64    `gorm:"column:saved_price;default:0" json:"saved_price"`           // Product price at the time it was saved
+	TargetPrice       float64    `gorm:"column:target_price;default:0" json:"target_price"`         // Optional: alert below this price instead of SavedPrice
+	LastAlertPrice    float64    `gorm:"column:last_alert_price;default:0" json:"last_alert_price"` // Price of the last price-drop alert sent (0 = none)
+	PriceAlertedAt    *time.Time `gorm:"column:price_alerted_at" json:"price_alerted_at,omitempty"`
+	StockAlertedAt    *time.Time `gorm:"column:stock_alerted_at" json:"stock_alerted_at,omitempty"`
+
 	CreatedAt time.Time      `gorm:"column:created_at" json:"created_at"`
 	UpdatedAt time.Time      `gorm:"column:updated_at" json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
 	// Relations
-	User    User    `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user,omitempty"`
-	Product Product `gorm:"foreignKey:ProductID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"product,omitempty"`
+	User       User                `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"user,omitempty"`
+	Product    Product             `gorm:"foreignKey:ProductID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"product,omitempty"`
+	Collection *WishlistCollection `gorm:"foreignKey:CollectionID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL" json:"collection,omitempty"`
 }
 
 func (WishlistItem) TableName() string {
 	return "wishlist_items"
 }
+
+// PriceThreshold returns the price the product has to fall below to trigger a price-drop alert
+func (w WishlistItem) PriceThreshold() float64 {
+	if w.TargetPrice > 0 {
+		return w.TargetPrice
+	}
+	return w.SavedPrice
+}
+
+// WishlistCollection is a named group of wishlist items (e.g., "Kado Lebaran", "Dapur baru")
+// A collection can be published read-only under an unguessable share token
+type WishlistCollection struct {
+	ID          uint           `gorm:"primaryKey;column:id" json:"id"`
+	UserID      uint           `gorm:"column:user_id;not null;index" json:"user_id"`
+	Name        string         `gorm:"column:name;size:100;not null" json:"name"`
+	Description string         `gorm:"column:description;type:text" json:"description,omitempty"`
+	ShareToken  *string        `gorm:"column:share_token;size:64;uniqueIndex" json:"share_token,omitempty"` // nil = not shared
+	SharedAt    *time.Time     `gorm:"column:shared_at" json:"shared_at,omitempty"`
+	CreatedAt   time.Time      `gorm:"column:created_at" json:"created_at"`
+	UpdatedAt   time.Time      `gorm:"column:updated_at" json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+
+	// Relations
+	User  User           `gorm:"foreignKey:UserID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Items []WishlistItem `gorm:"foreignKey:CollectionID" json:"items,omitempty"`
+
+	// Virtual fields
+	ItemCount int `gorm:"-" json:"item_count"`
+}
+
+func (WishlistCollection) TableName() string {
+	return "wishlist_collections"
+}
+
+// IsShared reports whether the collection is currently published
+func (w WishlistCollection) IsShared() bool {
+	return w.ShareToken != nil && *w.ShareToken != ""
+}
@@ -0,0 +1,63 @@
+package moderation
+
+import (
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"gorm.io/gorm"
+)
+
+// IsBlocked reports whether either user has blocked the other
+func IsBlocked(db *gorm.DB, userA, userB uint) bool {
+	var count int64
+	if err := db.Model(&model.UserBlock{}).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userA, userB, userB, userA).
+		Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// IsBlockedByAny reports whether the user and any of others have blocked each other
+func IsBlockedByAny(db *gorm.DB, userID uint, others []uint) bool {
+	if len(others) == 0 {
+		return false
+	}
+	var count int64
+	if err := db.Model(&model.UserBlock{}).
+		Where("(blocker_id = ? AND blocked_id IN ?) OR (blocked_id = ? AND blocker_id IN ?)", userID, others, userID, others).
+		Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// IsChatBlocked reports whether a block stands between the user and the other side of the chat. The
+// shop's owner and staff share one side of a shop chat, so a block between the buyer and any of them
+// cuts off the whole team.
+func IsChatBlocked(db *gorm.DB, chat model.Chat, userID uint) bool {
+	if chat.ShopID == nil {
+		return IsBlocked(db, userID, chatroom.Counterpart(db, chat, userID))
+	}
+	// User1 is always the buyer of a shop chat
+	return IsBlockedByAny(db, chat.User1ID, chatroom.Team(db, *chat.ShopID))
+}
+
+// BlockedUserIDs returns everyone the user has blocked or been blocked by
+func BlockedUserIDs(db *gorm.DB, userID uint) []uint {
+	var blocks []model.UserBlock
+	if err := db.Select("blocker_id", "blocked_id").
+		Where("blocker_id = ? OR blocked_id = ?", userID, userID).
+		Find(&blocks).Error; err != nil {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(blocks))
+	for _, b := range blocks {
+		if b.BlockerID == userID {
+			ids = append(ids, b.BlockedID)
+		} else {
+			ids = append(ids, b.BlockerID)
+		}
+	}
+	return ids
+}
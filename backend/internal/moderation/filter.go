@@ -0,0 +1,97 @@
+package moderation
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+)
+
+// Action is what the content filter does with a message
+type Action string
+
+const (
+	Allow  Action = "allow"
+	Flag   Action = "flag"   // Deliver, but queue for admin review
+	Reject Action = "reject" // Refuse to send
+)
+
+// Verdict is the outcome of checking a message; Reasons lists the rules that matched
+type Verdict struct {
+	Action  Action
+	Reasons []string
+}
+
+var (
+	linkPattern  = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+|\b[a-z0-9-]+\.(?:com|net|org|id|co|io|me|ly|link|xyz|info|biz)\b`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s().-]{7,}\d`)
+)
+
+// Check runs the configured rules against a message. Each rule is configured through the
+// environment as off, flag or reject; links are flagged and phone numbers allowed by default.
+func Check(content string) Verdict {
+	v := Verdict{Action: Allow}
+
+	if action := ruleAction("CHAT_FILTER_LINKS", Flag); action != Allow && linkPattern.MatchString(content) {
+		v.add(action, "link")
+	}
+	if action := ruleAction("CHAT_FILTER_PHONES", Allow); action != Allow && containsPhoneNumber(content) {
+		v.add(action, "phone_number")
+	}
+	if action := ruleAction("CHAT_BANNED_WORDS_ACTION", Reject); action != Allow && containsBannedWord(content) {
+		v.add(action, "banned_word")
+	}
+	return v
+}
+
+func (v *Verdict) add(action Action, reason string) {
+	v.Reasons = append(v.Reasons, reason)
+	if action == Reject || v.Action == Allow {
+		v.Action = action
+	}
+}
+
+func ruleAction(key string, def Action) Action {
+	switch Action(strings.ToLower(util.Getenv(key, string(def)))) {
+	case Flag:
+		return Flag
+	case Reject:
+		return Reject
+	case "off", Allow:
+		return Allow
+	default:
+		return def
+	}
+}
+
+// containsPhoneNumber looks for 9 or more digits written as one number, allowing separators
+func containsPhoneNumber(content string) bool {
+	for _, match := range phonePattern.FindAllString(content, -1) {
+		digits := 0
+		for _, r := range match {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		if digits >= 9 {
+			return true
+		}
+	}
+	return false
+}
+
+// containsBannedWord matches whole words from the comma-separated CHAT_BANNED_WORDS, ignoring case
+func containsBannedWord(content string) bool {
+	var words []string
+	for _, w := range strings.Split(util.Getenv("CHAT_BANNED_WORDS", ""), ",") {
+		if w = strings.TrimSpace(w); w != "" {
+			words = append(words, regexp.QuoteMeta(w))
+		}
+	}
+	if len(words) == 0 {
+		return false
+	}
+	re, err := regexp.Compile(`(?i)\b(?:` + strings.Join(words, "|") + `)\b`)
+	return err == nil && re.MatchString(content)
+}
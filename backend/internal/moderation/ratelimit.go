@@ -0,0 +1,27 @@
+package moderation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/pkg/kvstore"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+// AllowMessage counts a message against the user's per-minute limit and reports whether it may be sent.
+// The limit is shared across instances when Redis is available.
+func AllowMessage(userID uint) bool {
+	limit := util.Getenv("CHAT_RATE_LIMIT_PER_MIN", 20)
+	if limit <= 0 {
+		return true
+	}
+
+	window := time.Now().Unix() / 60
+	n, err := kvstore.IncrKey(fmt.Sprintf("chat:rate:%d:%d", userID, window), time.Minute)
+	if err != nil {
+		logrus.Errorf("Chat rate limit check failed for user %d: %v", userID, err)
+		return true
+	}
+	return n <= int64(limit)
+}
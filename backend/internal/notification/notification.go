@@ -0,0 +1,99 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/mailer"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/websockets"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Message describes a notification to deliver to a user
+type Message struct {
+	Type    string
+	Title   string
+	Body    string
+	Link    string // Relative app path, e.g. "/products/12"
+	Payload any    // Extra data for the client, stored as JSON
+}
+
+// Preference returns the user's channel preference for a notification type (or the default)
+func Preference(db *gorm.DB, userID uint, notificationType string) model.NotificationPreference {
+	var pref model.NotificationPreference
+	if err := db.Where("user_id = ? AND type = ?", userID, notificationType).First(&pref).Error; err != nil {
+		return model.DefaultNotificationPreference(userID, notificationType)
+	}
+	return pref
+}
+
+// Send delivers a notification through the channels the user opted into.
+// In-app notifications are persisted and pushed to every open tab; email is sent when enabled.
+// Returns the stored notification, or nil when the user disabled in-app delivery for this type.
+func Send(db *gorm.DB, userID uint, msg Message) (*model.Notification, error) {
+	pref := Preference(db, userID, msg.Type)
+
+	var stored *model.Notification
+	if pref.InApp {
+		n := model.Notification{
+			UserID: userID,
+			Type:   msg.Type,
+			Title:  msg.Title,
+			Body:   msg.Body,
+			Link:   msg.Link,
+		}
+		if msg.Payload != nil {
+			n.Payload, _ = json.Marshal(msg.Payload)
+		}
+		if err := db.Create(&n).Error; err != nil {
+			return nil, fmt.Errorf("failed to store notification: %w", err)
+		}
+		stored = &n
+		Push(db, n)
+	}
+
+	if pref.Email {
+		var user model.User
+		if err := db.Select("id", "email", "first_name").First(&user, userID).Error; err == nil && user.Email.String() != "" {
+			link := ""
+			if msg.Link != "" {
+				link = os.Getenv("APP_PUBLIC_URL") + msg.Link
+			}
+			if _, err := mailer.Enqueue(db, mailer.Email{
+				To:       []string{user.Email.String()},
+				Template: mailer.TemplateNotification,
+				Data: map[string]any{
+					"Name":  user.FirstName,
+					"Title": msg.Title,
+					"Body":  msg.Body,
+					"Link":  link,
+				},
+			}); err != nil {
+				logrus.Errorf("Failed to queue %s notification email to user %d: %v", msg.Type, userID, err)
+			}
+		}
+	}
+
+	return stored, nil
+}
+
+// Push sends a stored notification to all of the user's connected tabs as a notification event
+func Push(db *gorm.DB, n model.Notification) {
+	err := websockets.SendEvent(n.UserID, websockets.EventNotification, map[string]interface{}{
+		"notification": n,
+		"unread_count": UnreadCount(db, n.UserID),
+	})
+	if err != nil {
+		logrus.Errorf("Failed to push notification %d: %v", n.ID, err)
+	}
+}
+
+// UnreadCount returns the number of unread notifications for a user
+func UnreadCount(db *gorm.DB, userID uint) int64 {
+	var count int64
+	db.Model(&model.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count)
+	return count
+}
@@ -0,0 +1,71 @@
+package pricing
+
+import (
+	"errors"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidToken is returned for special price tokens that are unknown, expired, or belong to
+// another user or product
+var ErrInvalidToken = errors.New("special price token is invalid or expired")
+
+// Quote is the price a user pays for a product
+type Quote struct {
+	ProductID      uint       `json:"product_id"`
+	BasePrice      float64    `json:"base_price"`
+	Price          float64    `json:"price"`
+	SpecialPriceID *uint      `json:"special_price_id,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"` // When the special price stops applying
+}
+
+// TokenTTL is how long an accepted offer's special price stays valid
+func TokenTTL() time.Duration {
+	return time.Duration(util.Getenv("SPECIAL_PRICE_TTL_H", 24)) * time.Hour
+}
+
+// ForUser prices a product for a user, applying a special price token when one is given.
+// The token must have been issued to this user for this product and not have expired.
+func ForUser(db *gorm.DB, product model.Product, userID uint, token string) (Quote, error) {
+	q := Quote{ProductID: product.ID, BasePrice: product.Price, Price: product.Price}
+	if token == "" {
+		return q, nil
+	}
+
+	var sp model.SpecialPrice
+	if err := db.Where("token = ? AND user_id = ? AND product_id = ? AND expires_at > ?", token, userID, product.ID, time.Now()).
+		First(&sp).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return q, ErrInvalidToken
+		}
+		return q, err
+	}
+
+	// Never charge more than the current list price if the seller has since lowered it
+	if sp.Price < q.Price {
+		q.Price = sp.Price
+	}
+	q.SpecialPriceID = &sp.ID
+	q.ExpiresAt = &sp.ExpiresAt
+	return q, nil
+}
+
+// Issue grants the buyer of an accepted offer a special price token
+func Issue(tx *gorm.DB, offer model.ChatOffer) (model.SpecialPrice, error) {
+	token, err := util.GenerateSecureToken(24)
+	if err != nil {
+		return model.SpecialPrice{}, err
+	}
+	sp := model.SpecialPrice{
+		Token:     token,
+		UserID:    offer.BuyerID,
+		ProductID: offer.ProductID,
+		OfferID:   offer.ID,
+		Price:     offer.Price,
+		ExpiresAt: time.Now().Add(TokenTTL()),
+	}
+	return sp, tx.Create(&sp).Error
+}
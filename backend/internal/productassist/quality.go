@@ -0,0 +1,141 @@
+package productassist
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Listing issues
+const (
+	IssueMissingImage       = "missing_image"
+	IssueMissingDescription = "missing_description"
+	IssueShortDescription   = "short_description"
+	IssueShortName          = "short_name"
+	IssueMissingCategory    = "missing_category"
+	IssueCategoryMismatch   = "category_mismatch"
+	IssueMissingPrice       = "missing_price"
+	IssuePriceBelowMarket   = "price_below_market"
+	IssuePriceAboveMarket   = "price_above_market"
+)
+
+// Issues lists every issue code
+var Issues = []string{
+	IssueMissingImage, IssueMissingDescription, IssueShortDescription, IssueShortName, IssueMissingCategory,
+	IssueCategoryMismatch, IssueMissingPrice, IssuePriceBelowMarket, IssuePriceAboveMarket,
+}
+
+// penalties are the points an issue takes off the score of 100
+var penalties = map[string]int{
+	IssueMissingImage:       30,
+	IssueMissingDescription: 25,
+	IssueShortDescription:   15,
+	IssueShortName:          10,
+	IssueMissingCategory:    15,
+	IssueCategoryMismatch:   10,
+	IssueMissingPrice:       20,
+	IssuePriceBelowMarket:   15,
+	IssuePriceAboveMarket:   10,
+}
+
+// Issue is one problem found in a listing
+type Issue struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Penalty int    `json:"penalty"`
+}
+
+// Quality is the advisory grade of a listing; it never blocks saving
+type Quality struct {
+	Score       int                 `json:"score"` // 0..100
+	Issues      []Issue             `json:"issues"`
+	Category    *CategorySuggestion `json:"category_suggestion,omitempty"`
+	MedianPrice float64             `json:"category_median_price,omitempty"`
+}
+
+// CheckListing grades a new listing with the rules and the classifier. It never calls the model or waits
+// for the catalog: before the first catalog build only the rules apply. images counts the product's
+// pictures including the main one.
+func CheckListing(db *gorm.DB, p model.Product, images int) Quality {
+	c := currentCatalog(db)
+	if c == nil {
+		c = &catalog{}
+	}
+	return c.grade(p, images, c.classify(p.Name+" "+p.Subtitle))
+}
+
+// ReviewListing grades a saved listing like CheckListing, but waits for the catalog and asks the model
+// for the category when the classifier is unsure
+func ReviewListing(ctx context.Context, db *gorm.DB, userID uint, p model.Product, images int) (Quality, error) {
+	c, err := loadCatalog(db)
+	if err != nil {
+		return Quality{}, err
+	}
+	suggestion, err := SuggestCategory(ctx, db, userID, p)
+	if err != nil {
+		logrus.Warnf("Category suggestion for product %d fell back to the classifier: %v", p.ID, err)
+	}
+	return c.grade(p, images, suggestion), nil
+}
+
+// grade applies the rules; suggestion may be nil
+func (c *catalog) grade(p model.Product, images int, suggestion *CategorySuggestion) Quality {
+	q := Quality{Issues: []Issue{}, Category: suggestion}
+	add := func(code, message string) {
+		q.Issues = append(q.Issues, Issue{Code: code, Message: message, Penalty: penalties[code]})
+	}
+
+	if images == 0 {
+		add(IssueMissingImage, "Add at least one product photo")
+	}
+	minDescription := util.Getenv("PRODUCT_QUALITY_MIN_DESCRIPTION", 150)
+	switch n := utf8.RuneCountInString(oneLine(p.Description)); {
+	case n == 0:
+		add(IssueMissingDescription, "Add a description")
+	case n < minDescription:
+		add(IssueShortDescription, fmt.Sprintf("The description has %d characters; aim for at least %d", n, minDescription))
+	}
+	if utf8.RuneCountInString(oneLine(p.Name)) < util.Getenv("PRODUCT_QUALITY_MIN_NAME", 10) {
+		add(IssueShortName, "Use a more descriptive name, e.g. brand, type and size")
+	}
+
+	if p.CategoryID == 0 {
+		add(IssueMissingCategory, "Choose a category so buyers can find the product")
+	} else if suggestion != nil && suggestion.CategoryID != p.CategoryID &&
+		suggestion.Confidence >= util.Getenv("PRODUCT_CATEGORY_MISMATCH_CONFIDENCE", 0.8) {
+		add(IssueCategoryMismatch, fmt.Sprintf("Similar products are listed under %s", suggestion.CategoryName))
+	}
+
+	// Prices are compared within the chosen category, or the suggested one while none is chosen
+	category := p.CategoryID
+	if category == 0 && suggestion != nil {
+		category = suggestion.CategoryID
+	}
+	ratio := util.Getenv("PRODUCT_PRICE_OUTLIER_RATIO", 4.0)
+	median := c.medians[category]
+	switch {
+	case p.Price <= 0:
+		add(IssueMissingPrice, "Set a price")
+	case median <= 0 || c.priced[category] < util.Getenv("PRODUCT_PRICE_MIN_SAMPLES", 5) || ratio <= 1:
+		// Too few prices in the category to judge
+	case p.Price < median/ratio:
+		add(IssuePriceBelowMarket, fmt.Sprintf("The price is far below the category median of %s; check for a typo", util.FormatIDR(int(median))))
+	case p.Price > median*ratio:
+		add(IssuePriceAboveMarket, fmt.Sprintf("The price is far above the category median of %s; check for a typo", util.FormatIDR(int(median))))
+	}
+	if median > 0 {
+		q.MedianPrice = median
+	}
+
+	q.Score = 100
+	for _, issue := range q.Issues {
+		q.Score -= issue.Penalty
+	}
+	q.Score = max(q.Score, 0)
+	return q
+}
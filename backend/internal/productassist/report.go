@@ -0,0 +1,146 @@
+package productassist
+
+import (
+	"math"
+	"slices"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"gorm.io/gorm"
+)
+
+// ShopQuality summarises the listings of one shop
+type ShopQuality struct {
+	ShopID   uint           `json:"shop_id"`
+	ShopName string         `json:"shop_name"`
+	Listings int            `json:"listings"`
+	AvgScore float64        `json:"avg_score"`
+	Poor     int            `json:"poor"`   // Listings scoring below PRODUCT_QUALITY_POOR_SCORE
+	Issues   map[string]int `json:"issues"` // Listings per issue code
+}
+
+// ListingQuality is the grade of one listing
+type ListingQuality struct {
+	ProductID  uint   `json:"product_id"`
+	Name       string `json:"name"`
+	Slug       string `json:"slug"`
+	CategoryID uint   `json:"category_id"`
+	Quality
+}
+
+// ShopReport grades every listing with the rules and the classifier (never the model) and summarises
+// them per shop, weakest shops first
+func ShopReport(db *gorm.DB) ([]ShopQuality, error) {
+	poor := util.Getenv("PRODUCT_QUALITY_POOR_SCORE", 60)
+	byShop := map[uint]*ShopQuality{}
+	totals := map[uint]int{}
+	err := eachListing(db, 0, func(p model.Product, q Quality) {
+		s := byShop[p.ShopID]
+		if s == nil {
+			s = &ShopQuality{ShopID: p.ShopID, Issues: map[string]int{}}
+			byShop[p.ShopID] = s
+		}
+		s.Listings++
+		totals[p.ShopID] += q.Score
+		if q.Score < poor {
+			s.Poor++
+		}
+		for _, issue := range q.Issues {
+			s.Issues[issue.Code]++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, 0, len(byShop))
+	for id := range byShop {
+		ids = append(ids, id)
+	}
+	var shops []model.Shop
+	if len(ids) > 0 {
+		if err := db.Select("id", "name").Where("id IN ?", ids).Find(&shops).Error; err != nil {
+			return nil, err
+		}
+	}
+	for _, shop := range shops {
+		byShop[shop.ID].ShopName = shop.Name
+	}
+
+	report := make([]ShopQuality, 0, len(byShop))
+	for id, s := range byShop {
+		s.AvgScore = math.Round(float64(totals[id])/float64(s.Listings)*10) / 10
+		report = append(report, *s)
+	}
+	slices.SortFunc(report, func(a, b ShopQuality) int {
+		if a.AvgScore != b.AvgScore {
+			if a.AvgScore < b.AvgScore {
+				return -1
+			}
+			return 1
+		}
+		return int(a.ShopID) - int(b.ShopID)
+	})
+	return report, nil
+}
+
+// ShopListings grades the listings of one shop, weakest first, optionally only those with an issue
+func ShopListings(db *gorm.DB, shopID uint, issue string) ([]ListingQuality, error) {
+	listings := []ListingQuality{}
+	err := eachListing(db, shopID, func(p model.Product, q Quality) {
+		if issue != "" && !slices.ContainsFunc(q.Issues, func(i Issue) bool { return i.Code == issue }) {
+			return
+		}
+		listings = append(listings, ListingQuality{ProductID: p.ID, Name: p.Name, Slug: p.Slug, CategoryID: p.CategoryID, Quality: q})
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(listings, func(a, b ListingQuality) int { return a.Score - b.Score })
+	return listings, nil
+}
+
+// eachListing grades the listings of a shop, or of every shop for 0, in batches
+func eachListing(db *gorm.DB, shopID uint, fn func(model.Product, Quality)) error {
+	c, err := loadCatalog(db)
+	if err != nil {
+		return err
+	}
+
+	query := db.Model(&model.Product{}).
+		Select("id", "name", "slug", "subtitle", "description", "image_url", "price", "category_id", "sub_category_id", "shop_id")
+	if shopID != 0 {
+		query = query.Where("shop_id = ?", shopID)
+	}
+	var batch []model.Product
+	return query.FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+		ids := make([]uint, len(batch))
+		for i, p := range batch {
+			ids[i] = p.ID
+		}
+		var counts []struct {
+			ProductID uint
+			Images    int
+		}
+		if err := db.Model(&model.ProductImage{}).
+			Select("product_id, COUNT(*) AS images").
+			Where("product_id IN ?", ids).
+			Group("product_id").
+			Scan(&counts).Error; err != nil {
+			return err
+		}
+		images := map[uint]int{}
+		for _, n := range counts {
+			images[n.ProductID] = n.Images
+		}
+
+		for _, p := range batch {
+			n := images[p.ID]
+			if p.ImageURL != "" {
+				n++
+			}
+			fn(p, c.grade(p, n, c.classify(p.Name+" "+p.Subtitle)))
+		}
+		return nil
+	}).Error
+}
@@ -0,0 +1,246 @@
+package retention
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/attachment"
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/audit"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Retention modes: purge deletes old messages, anonymize keeps who wrote when but removes what was written
+const (
+	ModeAnonymize = "anonymize"
+	ModePurge     = "purge"
+)
+
+// RedactedContent replaces the content of anonymized messages
+const RedactedContent = "[Message removed by retention policy]"
+
+const job = "chat-retention"
+
+var (
+	// ErrDisabled is returned by Run when CHAT_RETENTION_DAYS is not set
+	ErrDisabled = errors.New("chat retention is disabled")
+	// ErrRunning is returned by Run while another run is in progress
+	ErrRunning = errors.New("chat retention is already running")
+
+	running sync.Mutex
+
+	// errHeld is returned by apply for a chat put on legal hold after it was selected
+	errHeld = errors.New("chat is on legal hold")
+)
+
+// Policy is the configured retention
+type Policy struct {
+	Days int    `json:"days"` // 0 keeps messages forever
+	Mode string `json:"mode"`
+}
+
+// Result summarises one run
+type Result struct {
+	Policy      Policy    `json:"policy"`
+	Cutoff      time.Time `json:"cutoff"`
+	Chats       int       `json:"chats"`
+	Messages    int64     `json:"messages"`
+	Attachments int       `json:"attachments"`
+	Held        int64     `json:"held"`   // Chats with old messages skipped because of a legal hold
+	Failed      int       `json:"failed"` // Chats that could not be processed; retried next run
+}
+
+// CurrentPolicy reads CHAT_RETENTION_DAYS and CHAT_RETENTION_MODE
+func CurrentPolicy() Policy {
+	p := Policy{
+		Days: max(util.Getenv("CHAT_RETENTION_DAYS", 0), 0),
+		Mode: strings.ToLower(util.Getenv("CHAT_RETENTION_MODE", ModeAnonymize)),
+	}
+	if p.Mode != ModePurge {
+		p.Mode = ModeAnonymize
+	}
+	return p
+}
+
+// Enabled reports whether old messages are removed at all
+func (p Policy) Enabled() bool {
+	return p.Days > 0
+}
+
+// Start runs the retention job every CHAT_RETENTION_INTERVAL_H hours when a policy is configured
+func Start(db *gorm.DB) {
+	if !CurrentPolicy().Enabled() {
+		return
+	}
+	interval := time.Duration(util.Getenv("CHAT_RETENTION_INTERVAL_H", 24)) * time.Hour
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			result, err := Run(db)
+			if err != nil && !errors.Is(err, ErrRunning) {
+				logrus.Errorf("Chat retention failed: %v", err)
+			} else if result.Chats > 0 {
+				logrus.Infof("Chat retention: %s %d messages in %d chats (%d on legal hold)",
+					result.Policy.Mode, result.Messages, result.Chats, result.Held)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// Run applies the retention policy to every chat that is not under legal hold. Each chat is processed
+// in its own transaction and recorded in the audit log.
+func Run(db *gorm.DB) (Result, error) {
+	policy := CurrentPolicy()
+	result := Result{Policy: policy}
+	if !policy.Enabled() {
+		return result, ErrDisabled
+	}
+	if !running.TryLock() {
+		return result, ErrRunning
+	}
+	defer running.Unlock()
+
+	result.Cutoff = time.Now().AddDate(0, 0, -policy.Days)
+	expired := db.Unscoped().Model(&model.Message{}).Select("chat_id").Where("created_at < ?", result.Cutoff)
+	if policy.Mode == ModeAnonymize {
+		expired = expired.Where("redacted_at IS NULL")
+	}
+
+	var chatIDs []uint
+	if err := db.Unscoped().Model(&model.Chat{}).
+		Where("legal_hold_at IS NULL AND id IN (?)", expired).
+		Order("id ASC").
+		Pluck("id", &chatIDs).Error; err != nil {
+		return result, err
+	}
+	db.Unscoped().Model(&model.Chat{}).
+		Where("legal_hold_at IS NOT NULL AND id IN (?)", expired).
+		Count(&result.Held)
+
+	for _, chatID := range chatIDs {
+		messages, files, err := apply(db, chatID, policy.Mode, result.Cutoff)
+		if errors.Is(err, errHeld) {
+			result.Held++
+			continue
+		}
+		entry := audit.Update("chat_messages", chatID)
+		if policy.Mode == ModePurge {
+			entry = audit.Delete("chat_messages", chatID)
+		}
+		entry.After(map[string]any{
+			"mode":        policy.Mode,
+			"cutoff":      result.Cutoff,
+			"messages":    messages,
+			"attachments": len(files),
+		})
+		if err != nil {
+			result.Failed++
+			audit.LogSystem(db, job, entry.Failed(err))
+			logrus.Errorf("Chat retention failed for chat %d: %v", chatID, err)
+			continue
+		}
+		audit.LogSystem(db, job, entry.Success(fmt.Sprintf("Retention %s of %d messages older than %d days", policy.Mode, messages, policy.Days)))
+
+		// Files go only after the rows are gone, so a failed transaction never leaves dangling rows
+		for _, a := range files {
+			attachment.Remove(a)
+		}
+		result.Chats++
+		result.Messages += messages
+		result.Attachments += len(files)
+	}
+	return result, nil
+}
+
+// apply purges or anonymizes a chat's messages older than cutoff, together with their attachments, any
+// attachment that was uploaded but never sent, and the copies kept in events and webhook deliveries
+func apply(db *gorm.DB, chatID uint, mode string, cutoff time.Time) (int64, []model.ChatAttachment, error) {
+	var affected int64
+	var files []model.ChatAttachment
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Lock the chat and re-check the hold, so a hold placed since the run started wins.
+		// SQL Server has no FOR UPDATE; there the check runs without the lock.
+		lock := tx.Unscoped()
+		if tx.Dialector.Name() != "sqlserver" {
+			lock = lock.Clauses(clause.Locking{Strength: "UPDATE"})
+		}
+		var chat model.Chat
+		if err := lock.Select("id").Where("id = ? AND legal_hold_at IS NULL", chatID).Take(&chat).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errHeld
+			}
+			return err
+		}
+
+		old := func() *gorm.DB {
+			return tx.Unscoped().Model(&model.Message{}).Where("chat_id = ? AND created_at < ?", chatID, cutoff)
+		}
+
+		if err := tx.Where("chat_id = ? AND (message_id IN (?) OR (message_id IS NULL AND created_at < ?))",
+			chatID, old().Select("id"), cutoff).
+			Find(&files).Error; err != nil {
+			return err
+		}
+
+		var res *gorm.DB
+		if mode == ModePurge {
+			res = old().Delete(&model.Message{})
+		} else {
+			res = old().Where("redacted_at IS NULL").Updates(map[string]any{
+				"kind":            model.MessageText,
+				"content":         RedactedContent,
+				"payload":         nil,
+				"attachment_id":   nil,
+				"attachment_url":  "",
+				"attachment_type": "",
+				"redacted_at":     time.Now(),
+			})
+		}
+		if res.Error != nil {
+			return res.Error
+		}
+		affected = res.RowsAffected
+
+		// Offer notes are free text too; prices and statuses stay as the commercial record
+		if err := tx.Model(&model.ChatOffer{}).
+			Where("chat_id = ? AND created_at < ? AND note <> ''", chatID, cutoff).
+			Update("note", "").Error; err != nil {
+			return err
+		}
+
+		// Message events and the webhook deliveries made from them hold full copies of the messages
+		aggregate := fmt.Sprint(chatID)
+		messageEvents := func() *gorm.DB {
+			return tx.Model(&model.EventOutbox{}).
+				Where("type = ? AND aggregate_id = ? AND created_at < ?", events.TypeMessageSent, aggregate, cutoff)
+		}
+		if err := tx.Where("event_type = ? AND aggregate_id = ? AND (created_at < ? OR event_id IN (?))",
+			events.TypeMessageSent, aggregate, cutoff, messageEvents().Select("event_id")).
+			Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := messageEvents().Delete(&model.EventOutbox{}).Error; err != nil {
+			return err
+		}
+
+		if len(files) > 0 {
+			return tx.Delete(&files).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+	return affected, files, nil
+}
@@ -46,6 +46,12 @@ func Routes() {
 	r.PATCH("/products/:id", handler.UpdateProduct(database.DB))  // Protected: Update product (alias)
 	r.DELETE("/products/:id", handler.DeleteProduct(database.DB)) // Protected: Delete product
 
+	r.POST("/products/copy-suggestions", handler.SuggestProductCopy(database.DB)) // Protected: LLM description and SEO suggestion (not saved)
+	r.GET("/products/:id/quality", handler.GetProductQuality(database.DB))        // Protected: Listing grade with the LLM category suggestion
+
+	// Semantic search - Public (Products ranked by meaning, optionally blended with keywords)
+	r.GET("/search/semantic", handler.SemanticSearch(database.DB)) // ?q=, ?mode=semantic|hybrid, ?weight=, ?page=, ?limit=
+
 	// Category endpoints - Public
 	r.GET("/categories", handler.GET_DEFAULT_TABLE(database.DB, &model.Category{}, []string{}))                               // Get all categories
 	r.GET("/categories/sub-categories", handler.GET_DEFAULT_TABLE(database.DB, &model.Category{}, []string{"SubCategories"})) // Get all categories
@@ -56,15 +62,25 @@ func Routes() {
 	// Wishlist endpoints - Protected (User's wishlist for saved products)
 	r.GET("/my-wishlist", handler.GetMyWishlist(database.DB))          // Get user's wishlist
 	r.POST("/wishlist/add", handler.AddToWishlist(database.DB))        // Add product to wishlist
-	r.PUT("/wishlist/:id", handler.UpdateWishlistItem(database.DB))    // Update wishlist item notes
+	r.PUT("/wishlist/:id", handler.UpdateWishlistItem(database.DB))    // Update wishlist item notes & alerts
 	r.PATCH("/wishlist/:id", handler.UpdateWishlistItem(database.DB))  // Update wishlist item (alias)
 	r.DELETE("/wishlist/:id", handler.RemoveFromWishlist(database.DB)) // Remove item from wishlist
 	r.DELETE("/wishlist/clear", handler.ClearWishlist(database.DB))    // Clear entire wishlist
+	r.PUT("/wishlist/:id/move", handler.MoveWishlistItem(database.DB)) // Move item to another collection
+
+	// Wishlist collections - Protected (named lists), shared view is Public
+	r.GET("/wishlist/collections", handler.GetMyWishlistCollections(database.DB))               // Get user's collections
+	r.POST("/wishlist/collections", handler.CreateWishlistCollection(database.DB))              // Create collection
+	r.PUT("/wishlist/collections/:id", handler.UpdateWishlistCollection(database.DB))           // Rename collection
+	r.DELETE("/wishlist/collections/:id", handler.DeleteWishlistCollection(database.DB))        // Delete collection (items kept)
+	r.POST("/wishlist/collections/:id/share", handler.ShareWishlistCollection(database.DB))     // Publish share link
+	r.DELETE("/wishlist/collections/:id/share", handler.UnshareWishlistCollection(database.DB)) // Revoke share link
+	r.GET("/wishlist/shared/:token", handler.GetSharedWishlistCollection(database.DB))          // Public: read-only shared collection
 
 	// Chat endpoints - Protected (User messaging system)
 	r.GET("/chats", handler.GetMyChats(database.DB))                          // Get all user's chats
 	r.POST("/chats", handler.GetOrCreateChat(database.DB))                    // Get or create chat with another user
-	r.GET("/chats/:id/messages", handler.GetChatMessages(database.DB))        // Get messages in a chat
+	r.GET("/chats/:id/messages", handler.GetChatMessages(database.DB))        // Get messages (?before_id=, ?after_id=, ?around_id=)
 	r.POST("/chats/:id/messages", handler.SendMessage(database.DB))           // Send message in a chat
 	r.PUT("/chats/:id/read", handler.MarkChatRead(database.DB))               // Mark all messages in chat as read
 	r.PUT("/messages/:id/received", handler.MarkMessageReceived(database.DB)) // Mark message as received
@@ -74,6 +90,69 @@ func Routes() {
 	r.GET("/messages/unread", handler.GetUnreadMessages(database.DB))         // Get all unread messages
 	r.GET("/messages/unread/count", handler.GetUnreadCount(database.DB))      // Get unread count
 
+	// Message search - Protected (Only searches the caller's own chats)
+	r.GET("/chats/:id/messages/search", handler.SearchChatMessages(database.DB)) // Search one chat (?q=, ?before_id=)
+	r.GET("/messages/search", handler.SearchMessages(database.DB))               // Search all of the user's chats
+
+	// Chat attachments - Protected (Private files, served to chat participants only)
+	r.POST("/chats/:id/attachments", handler.UploadChatAttachment(database.DB))               // Upload image/PDF/video for a message
+	r.GET("/chat-attachments/:id", handler.DownloadChatAttachment(database.DB))               // Download attachment (participants only)
+	r.GET("/chat-attachments/:id/thumbnail", handler.GetChatAttachmentThumbnail(database.DB)) // Image thumbnail (participants only)
+
+	// Chat offers - Protected (Price negotiation between buyer and seller)
+	r.GET("/chats/:id/offers", handler.GetChatOffers(database.DB))            // List offers in a chat
+	r.POST("/chats/:id/offers", handler.CreateChatOffer(database.DB))         // Buyer proposes a price
+	r.POST("/chat-offers/:id/accept", handler.AcceptChatOffer(database.DB))   // Accept, issuing a special price to the buyer
+	r.POST("/chat-offers/:id/reject", handler.RejectChatOffer(database.DB))   // Reject the offer
+	r.POST("/chat-offers/:id/counter", handler.CounterChatOffer(database.DB)) // Counter with another price
+	r.GET("/special-prices", handler.GetMySpecialPrices(database.DB))         // Buyer's valid special prices
+
+	// Chat safety - Protected (Blocking and reporting abusive users)
+	r.GET("/blocks", handler.GetMyBlocks(database.DB))             // List blocked users
+	r.POST("/users/:id/block", handler.BlockUser(database.DB))     // Block a user
+	r.DELETE("/users/:id/block", handler.UnblockUser(database.DB)) // Unblock a user
+	r.POST("/reports", handler.ReportChat(database.DB))            // Report a user or received message
+
+	// Presence endpoints - Protected (Online status of chat partners)
+	r.GET("/presence", handler.GetPresence(database.DB))                     // Online/last seen for ?user_ids= (chat partners only)
+	r.GET("/presence/settings", handler.GetPresenceSettings(database.DB))    // Get presence privacy setting
+	r.PUT("/presence/settings", handler.UpdatePresenceSettings(database.DB)) // Hide/show online status
+
+	// Notification endpoints - Protected (In-app notification center)
+	r.GET("/notifications", handler.GetMyNotifications(database.DB))                        // Get user's notifications
+	r.GET("/notifications/unread/count", handler.GetUnreadNotificationCount(database.DB))   // Get unread count
+	r.PUT("/notifications/read-all", handler.MarkAllNotificationsRead(database.DB))         // Mark all as read
+	r.PUT("/notifications/:id/read", handler.MarkNotificationRead(database.DB))             // Mark one as read
+	r.GET("/notifications/preferences", handler.GetNotificationPreferences(database.DB))    // Get channel preferences per type
+	r.PUT("/notifications/preferences", handler.UpdateNotificationPreferences(database.DB)) // Update channel preferences per type
+
+	// Email endpoints - Admin (templates preview & outbox)
+	r.GET("/admin/email-templates", handler.GetEmailTemplates())                   // List templates
+	r.GET("/admin/email-templates/:name/preview", handler.PreviewEmailTemplate())  // Preview template with sample data
+	r.GET("/admin/email-outbox", handler.GetEmailOutbox(database.DB))              // List outbox
+	r.POST("/admin/email-outbox/:id/retry", handler.RetryEmailOutbox(database.DB)) // Re-queue failed email
+
+	// Chat moderation - Super admin only (Review queue for reports and filtered messages)
+	r.GET("/admin/chat-reports", handler.GetChatReports(database.DB))       // List reports (?status=open|dismissed|actioned)
+	r.PUT("/admin/chat-reports/:id", handler.ReviewChatReport(database.DB)) // Dismiss or action a report
+
+	// Chat records - Super admin only (Transcript export, legal hold and retention)
+	r.GET("/admin/chats/:id/export", handler.ExportChat(database.DB))           // Download transcript (?format=json|txt|pdf)
+	r.PUT("/admin/chats/:id/legal-hold", handler.SetChatLegalHold(database.DB)) // Place or lift a legal hold
+	r.GET("/admin/chat-retention", handler.GetChatRetention(database.DB))       // Retention policy and legal holds
+	r.POST("/admin/chat-retention/run", handler.RunChatRetention(database.DB))  // Apply retention now
+
+	// LLM usage - Super admin only (Tokens, latency and budgets per feature)
+	r.GET("/admin/llm-usage", handler.GetLLMUsage(database.DB)) // Usage report (?from=, ?to=, ?feature=)
+
+	// Listing quality - Super admin only (Rule-based grades per shop)
+	r.GET("/admin/listing-quality", handler.GetListingQuality(database.DB))              // Shops, weakest first
+	r.GET("/admin/listing-quality/:shop_id", handler.GetShopListingQuality(database.DB)) // Listings of a shop (?issue=)
+
+	// Semantic search index - Super admin only
+	r.GET("/admin/search-index", handler.GetSearchIndex(database.DB))              // Embedded and stale products
+	r.POST("/admin/search-index/rebuild", handler.RebuildSearchIndex(database.DB)) // Re-embed every product in the background
+
 	// Shop endpoints - Public
 	r.GET("/shops", handler.GET_DEFAULT_TABLE(database.DB, &model.Shop{}, []string{}))                    // Get all shops
 	r.GET("/shops/products", handler.GET_DEFAULT_TABLE(database.DB, &model.Shop{}, []string{"Products"})) // Get all shops
@@ -87,4 +166,33 @@ func Routes() {
 	r.GET("/my-shop/products", handler.GetMyShopProducts(database.DB))  // Get user's shop products
 	r.GET("/my-shop/check", handler.CheckShopAvailability(database.DB)) // Check if user can create shop
 
+	// Shop webhooks - Protected (Seller integrations, signed event delivery)
+	r.GET("/my-shop/webhooks", handler.GetMyWebhooks(database.DB))                                             // List endpoints
+	r.POST("/my-shop/webhooks", handler.CreateMyWebhook(database.DB))                                          // Register endpoint (returns secret once)
+	r.PUT("/my-shop/webhooks/:id", handler.UpdateMyWebhook(database.DB))                                       // Update URL/events, enable/disable
+	r.DELETE("/my-shop/webhooks/:id", handler.DeleteMyWebhook(database.DB))                                    // Delete endpoint
+	r.POST("/my-shop/webhooks/:id/rotate-secret", handler.RotateMyWebhookSecret(database.DB))                  // Generate new signing secret
+	r.POST("/my-shop/webhooks/:id/test", handler.TestMyWebhook(database.DB))                                   // Send ping event
+	r.GET("/my-shop/webhooks/:id/deliveries", handler.GetMyWebhookDeliveries(database.DB))                     // Delivery log
+	r.POST("/my-shop/webhooks/:id/deliveries/:delivery_id/redeliver", handler.RedeliverMyWebhook(database.DB)) // Redeliver past event
+
+	// Shop chat automation - Protected (Auto-replies and quick-reply templates for sellers)
+	r.GET("/my-shop/chat-settings", handler.GetMyChatSettings(database.DB))         // Greeting, away message, operating hours
+	r.PUT("/my-shop/chat-settings", handler.UpdateMyChatSettings(database.DB))      // Save chat settings
+	r.GET("/my-shop/quick-replies", handler.GetMyQuickReplies(database.DB))         // List templates
+	r.POST("/my-shop/quick-replies", handler.CreateMyQuickReply(database.DB))       // Create template ({buyer_name}, {product_name}, {shop_name})
+	r.PUT("/my-shop/quick-replies/:id", handler.UpdateMyQuickReply(database.DB))    // Update template
+	r.DELETE("/my-shop/quick-replies/:id", handler.DeleteMyQuickReply(database.DB)) // Delete template
+	r.GET("/chats/:id/quick-replies", handler.GetChatQuickReplies(database.DB))     // Templates rendered for a chat
+
+	// Shop inbox - Protected (Chats shared between a shop's owner and staff)
+	r.GET("/my-shop/inbox", handler.GetShopInbox(database.DB))                  // Shop chats (?assigned=me|unassigned|all, ?shop_id=)
+	r.GET("/my-shop/staff", handler.GetMyShopStaff(database.DB))                // List staff
+	r.POST("/my-shop/staff", handler.AddMyShopStaff(database.DB))               // Add staff by user_id or email
+	r.DELETE("/my-shop/staff/:user_id", handler.RemoveMyShopStaff(database.DB)) // Remove staff
+	r.PUT("/chats/:id/assign", handler.AssignChat(database.DB))                 // Assign to a team member (user_id 0 unassigns)
+
+	// Chat reply drafts - Protected (LLM suggestions for the shop's team; never sent automatically)
+	r.POST("/chats/:id/reply-drafts", handler.DraftChatReplies(database.DB)) // 1-3 drafts ({count, instructions})
+
 }
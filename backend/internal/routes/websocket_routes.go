@@ -7,5 +7,6 @@ import (
 
 func WebSocketRoutes() {
 	r := R.Group(util.GetPathOnly(util.Getenv("VITE_BACKEND", "/api")))
-	r.GET("/ws", websockets.WebsocketHandlerGin)
+	r.GET("/ws", websockets.WebsocketHandlerGin)            // Upgrade; authenticate with ?ticket=
+	r.POST("/ws/ticket", websockets.WebsocketTicketHandler) // Issue single-use connection ticket
 }
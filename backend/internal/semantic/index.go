@@ -0,0 +1,269 @@
+// Package semantic ranks products by the meaning of a search query rather than its exact words. Product
+// texts are embedded in the background through the LLM client and the query is compared against them,
+// optionally blended with a keyword score (hybrid mode).
+package semantic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
+	"github.com/faiz-muttaqin/lgs/backend/internal/llmusage"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/llm"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// batchSize is how many products are embedded per call
+const batchSize = 32
+
+var (
+	queue = make(chan uint, 1024)
+	sweep = make(chan struct{}, 1)
+)
+
+// Start re-embeds products when they are created or their text changes, and sweeps for products missing
+// from the index every SEARCH_REINDEX_INTERVAL_MIN minutes. Call before events.StartRelay.
+func Start(db *gorm.DB) {
+	if llm.Default() == nil {
+		return
+	}
+
+	events.On("semantic_index", func(e events.ProductCreated) error {
+		enqueue(e.Product.ID)
+		return nil
+	})
+	events.On("semantic_index", func(e events.ProductUpdated) error {
+		b, a := e.Before, e.After
+		if b.Name != a.Name || b.Subtitle != a.Subtitle || b.Description != a.Description ||
+			b.CategoryID != a.CategoryID || b.SubCategoryID != a.SubCategoryID {
+			enqueue(a.ID)
+		}
+		return nil
+	})
+
+	go worker(db)
+	go func() {
+		ticker := time.NewTicker(time.Duration(util.Getenv("SEARCH_REINDEX_INTERVAL_MIN", 30)) * time.Minute)
+		defer ticker.Stop()
+		for {
+			Reindex()
+			<-ticker.C
+		}
+	}()
+}
+
+// Reindex wakes the background sweep, which embeds every product that is missing or stale
+func Reindex() {
+	select {
+	case sweep <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue never blocks the event relay; a dropped ID is picked up by the next sweep
+func enqueue(productID uint) {
+	select {
+	case queue <- productID:
+	default:
+	}
+}
+
+func worker(db *gorm.DB) {
+	for {
+		select {
+		case id := <-queue:
+			// Collect what arrives shortly after, e.g. a bulk edit, into one call
+			ids := []uint{id}
+			timeout := time.After(2 * time.Second)
+		collect:
+			for len(ids) < batchSize {
+				select {
+				case id := <-queue:
+					ids = append(ids, id)
+				case <-timeout:
+					break collect
+				}
+			}
+			if _, err := Index(context.Background(), db, ids); err != nil {
+				logrus.Errorf("Embedding products %v failed: %v", ids, err)
+			}
+		case <-sweep:
+			if n, err := sweepStale(db); err != nil {
+				logrus.Errorf("Semantic index sweep failed: %v", err)
+			} else if n > 0 {
+				logrus.Infof("Semantic index: embedded %d products", n)
+			}
+		}
+	}
+}
+
+// sweepStale embeds products without a current embedding, in batches, and drops embeddings of deleted products
+func sweepStale(db *gorm.DB) (int, error) {
+	client := llmusage.For(db, llmusage.FeatureSearchIndex, 0)
+	modelName := modelKey(client)
+
+	db.Where("product_id NOT IN (?)", db.Model(&model.Product{}).Select("id")).Delete(&model.ProductEmbedding{})
+
+	// Bounded so a product that keeps failing cannot keep the sweep busy; the rest waits for the next run.
+	// A failing batch is skipped for the rest of this run rather than stopping it.
+	total := 0
+	var failed []uint
+	for range max(util.Getenv("SEARCH_REINDEX_MAX", 5000)/batchSize, 1) {
+		query := staleProducts(db, modelName)
+		if len(failed) > 0 {
+			query = query.Where("products.id NOT IN ?", failed)
+		}
+		var ids []uint
+		if err := query.Order("products.id").Limit(batchSize).Pluck("products.id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		n, err := Index(context.Background(), db, ids)
+		total += n
+		if errors.Is(err, llm.ErrNotConfigured) || errors.Is(err, llmusage.ErrBudgetExceeded) {
+			// Every other batch would fail the same way
+			return total, err
+		}
+		if err != nil {
+			logrus.Errorf("Embedding products %v failed, skipped until the next sweep: %v", ids, err)
+			failed = append(failed, ids...)
+			continue
+		}
+	}
+	return total, nil
+}
+
+func staleProducts(db *gorm.DB, modelName string) *gorm.DB {
+	return db.Model(&model.Product{}).
+		Joins("LEFT JOIN product_embeddings ON product_embeddings.product_id = products.id").
+		Where("product_embeddings.id IS NULL OR product_embeddings.model <> ? OR product_embeddings.content_hash = '' "+
+			"OR products.updated_at > product_embeddings.updated_at", modelName)
+}
+
+// Index embeds the given products. Products whose text did not change since they were embedded with the
+// current model are only marked as checked. It returns how many products were embedded.
+func Index(ctx context.Context, db *gorm.DB, productIDs []uint) (int, error) {
+	client := llmusage.For(db, llmusage.FeatureSearchIndex, 0)
+	if llm.Default() == nil {
+		return 0, llm.ErrNotConfigured
+	}
+	modelName := modelKey(client)
+
+	var products []model.Product
+	if err := db.Preload("Category").Preload("SubCategory").Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return 0, err
+	}
+	var existing []model.ProductEmbedding
+	if err := db.Select("id", "product_id", "model", "content_hash").Where("product_id IN ?", productIDs).Find(&existing).Error; err != nil {
+		return 0, err
+	}
+	known := map[uint]model.ProductEmbedding{}
+	for _, e := range existing {
+		known[e.ProductID] = e
+	}
+
+	var pending []model.ProductEmbedding
+	var texts []string
+	var unchanged []uint
+	for _, p := range products {
+		text := embeddingText(p)
+		hash := sha256.Sum256([]byte(text))
+		e := model.ProductEmbedding{ProductID: p.ID, Model: modelName, ContentHash: hex.EncodeToString(hash[:])}
+		if k, ok := known[p.ID]; ok && k.Model == e.Model && k.ContentHash == e.ContentHash {
+			unchanged = append(unchanged, p.ID)
+			continue
+		}
+		pending = append(pending, e)
+		texts = append(texts, text)
+	}
+	if len(unchanged) > 0 {
+		db.Model(&model.ProductEmbedding{}).Where("product_id IN ?", unchanged).Update("updated_at", time.Now())
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+
+	vectors, err := client.Embed(ctx, texts)
+	if err != nil {
+		return 0, err
+	}
+	if len(vectors) != len(pending) {
+		return 0, fmt.Errorf("expected %d embeddings, got %d", len(pending), len(vectors))
+	}
+	s := vectorStore(db)
+	for i, e := range pending {
+		e.Dims = len(vectors[i])
+		e.Vector = encodeVector(vectors[i])
+		if err := s.save(db, e, vectors[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(pending), nil
+}
+
+// embeddingText is what a product is found by
+func embeddingText(p model.Product) string {
+	parts := []string{p.Name}
+	if p.Subtitle != "" {
+		parts = append(parts, p.Subtitle)
+	}
+	if p.Category.Name != "" {
+		category := p.Category.Name
+		if p.SubCategory.Name != "" {
+			category += " > " + p.SubCategory.Name
+		}
+		parts = append(parts, "Category: "+category)
+	}
+	if desc := strings.Join(strings.Fields(p.Description), " "); desc != "" {
+		parts = append(parts, desc[:min(len(desc), 2000)])
+	}
+	return strings.ToValidUTF8(strings.Join(parts, "\n"), "")
+}
+
+// modelKey identifies the vector space; switching provider or model re-embeds everything
+func modelKey(c llm.Client) string {
+	return c.Provider() + "/" + c.EmbeddingModel()
+}
+
+// Status describes the state of the index
+type Status struct {
+	Configured bool   `json:"configured"`
+	Store      string `json:"store"`
+	Model      string `json:"model,omitempty"`
+	Products   int64  `json:"products"` // Active products
+	Indexed    int64  `json:"indexed"`  // Products with a current embedding
+	Stale      int64  `json:"stale"`    // Products waiting to be (re-)embedded
+}
+
+// CurrentStatus counts indexed and stale products for the current embedding model
+func CurrentStatus(db *gorm.DB) Status {
+	s := Status{Store: vectorStore(db).name(), Configured: llm.Default() != nil}
+	db.Model(&model.Product{}).Where("is_active = ?", true).Count(&s.Products)
+	if !s.Configured {
+		return s
+	}
+	s.Model = modelKey(llmusage.For(db, llmusage.FeatureSearchIndex, 0))
+	db.Model(&model.ProductEmbedding{}).Where("model = ?", s.Model).Count(&s.Indexed)
+	staleProducts(db, s.Model).Count(&s.Stale)
+	return s
+}
+
+// RebuildAll marks every embedding as stale and starts the sweep, e.g. after changing how products are embedded
+func RebuildAll(db *gorm.DB) error {
+	if err := db.Session(&gorm.Session{AllowGlobalUpdate: true}).
+		Model(&model.ProductEmbedding{}).UpdateColumn("content_hash", "").Error; err != nil {
+		return err
+	}
+	Reindex()
+	return nil
+}
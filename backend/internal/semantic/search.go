@@ -0,0 +1,279 @@
+package semantic
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/llmusage"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/kvstore"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/llm"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Search modes: semantic ranks by vector similarity only, hybrid blends it with a keyword score
+const (
+	ModeSemantic = "semantic"
+	ModeHybrid   = "hybrid"
+)
+
+// Modes lists the supported modes
+var Modes = []string{ModeSemantic, ModeHybrid}
+
+// Query is a product search
+type Query struct {
+	Text   string
+	Mode   string
+	Weight float64 // Share of the vector score in hybrid mode, 0..1; 0 uses SEARCH_HYBRID_VECTOR_WEIGHT
+	Limit  int
+	Offset int
+}
+
+// Result is a product with its scores; scores are 0..1
+type Result struct {
+	Product      model.Product `json:"product"`
+	Score        float64       `json:"score"`
+	VectorScore  float64       `json:"vector_score"`
+	KeywordScore float64       `json:"keyword_score"`
+}
+
+// Page is one page of results. Degraded is set when hybrid search fell back to keywords only because
+// the embedding provider was unavailable.
+type Page struct {
+	Results  []Result `json:"results"`
+	Total    int      `json:"total"` // Ranked candidates, at most SEARCH_CANDIDATES
+	Mode     string   `json:"mode"`
+	Degraded bool     `json:"degraded,omitempty"`
+}
+
+type scored struct {
+	id      uint
+	vector  float64
+	keyword float64
+	score   float64
+}
+
+// Search ranks active products for the query. Semantic mode fails with llm.ErrNotConfigured (or the
+// provider's error) when the query cannot be embedded; hybrid mode then degrades to keyword ranking.
+func Search(ctx context.Context, db *gorm.DB, q Query) (Page, error) {
+	page := Page{Mode: q.Mode, Results: []Result{}}
+	candidates := util.Getenv("SEARCH_CANDIDATES", 200)
+	weight := q.Weight
+	if weight <= 0 || weight > 1 {
+		weight = min(max(util.Getenv("SEARCH_HYBRID_VECTOR_WEIGHT", 0.6), 0), 1)
+	}
+
+	byID := map[uint]*scored{}
+	get := func(id uint) *scored {
+		if byID[id] == nil {
+			byID[id] = &scored{id: id}
+		}
+		return byID[id]
+	}
+
+	client := llmusage.For(db, llmusage.FeatureSearch, 0)
+	vector, err := queryVector(ctx, client, q.Text)
+	switch {
+	case err != nil && q.Mode == ModeSemantic:
+		return page, err
+	case err != nil:
+		logrus.Warnf("Semantic search falls back to keywords: %v", err)
+		page.Degraded = true
+		weight = 0
+	default:
+		hits, err := vectorStore(db).nearest(db, modelKey(client), vector, candidates)
+		if err != nil {
+			return page, err
+		}
+		for _, h := range hits {
+			get(h.ProductID).vector = h.Score
+		}
+	}
+
+	if q.Mode == ModeHybrid {
+		keywords, err := keywordScores(db, q.Text, candidates)
+		if err != nil {
+			return page, err
+		}
+		var missing []uint
+		for id, score := range keywords {
+			if _, ok := byID[id]; !ok {
+				missing = append(missing, id)
+			}
+			get(id).keyword = score
+		}
+		// Keyword matches outside the vector top list still get their real similarity
+		if vector != nil && len(missing) > 0 {
+			scores, err := vectorStore(db).similarity(db, modelKey(client), vector, missing)
+			if err != nil {
+				return page, err
+			}
+			for id, s := range scores {
+				byID[id].vector = s
+			}
+		}
+	}
+	if len(byID) == 0 {
+		return page, nil
+	}
+
+	// Only active products are shown; inactive and deleted ones may still be in the index
+	ids := make([]uint, 0, len(byID))
+	for id := range byID {
+		ids = append(ids, id)
+	}
+	var active []uint
+	if err := db.Model(&model.Product{}).Where("id IN ? AND is_active = ?", ids, true).Pluck("id", &active).Error; err != nil {
+		return page, err
+	}
+
+	minScore := util.Getenv("SEARCH_MIN_SCORE", 0.0)
+	ranked := make([]*scored, 0, len(active))
+	for _, id := range active {
+		s := byID[id]
+		s.vector = min(max(s.vector, 0), 1)
+		s.score = s.vector
+		if q.Mode == ModeHybrid {
+			s.score = weight*s.vector + (1-weight)*s.keyword
+		}
+		if s.score > 0 && s.score >= minScore {
+			ranked = append(ranked, s)
+		}
+	}
+	slices.SortFunc(ranked, func(a, b *scored) int {
+		if a.score != b.score {
+			if a.score > b.score {
+				return -1
+			}
+			return 1
+		}
+		return int(a.id) - int(b.id)
+	})
+	page.Total = len(ranked)
+	if q.Offset >= len(ranked) {
+		return page, nil
+	}
+	ranked = ranked[q.Offset:min(q.Offset+q.Limit, len(ranked))]
+
+	pageIDs := make([]uint, len(ranked))
+	for i, s := range ranked {
+		pageIDs[i] = s.id
+	}
+	var products []model.Product
+	if err := db.Preload("Category").Preload("SubCategory").Preload("Shop").Preload("Images").
+		Where("id IN ?", pageIDs).Find(&products).Error; err != nil {
+		return page, err
+	}
+	found := map[uint]model.Product{}
+	for _, p := range products {
+		found[p.ID] = p
+	}
+	for _, s := range ranked {
+		if p, ok := found[s.id]; ok {
+			page.Results = append(page.Results, Result{Product: p, Score: s.score, VectorScore: s.vector, KeywordScore: s.keyword})
+		}
+	}
+	return page, nil
+}
+
+// queryVector embeds the query, caching vectors of repeated queries for SEARCH_QUERY_CACHE_MIN minutes
+func queryVector(ctx context.Context, client llm.Client, text string) ([]float32, error) {
+	if llm.Default() == nil {
+		return nil, llm.ErrNotConfigured
+	}
+	sum := sha256.Sum256([]byte(strings.ToLower(text)))
+	key := "search:qvec:" + modelKey(client) + ":" + hex.EncodeToString(sum[:16])
+	if cached, err := kvstore.GetKey(key); err == nil && cached != "" {
+		if b, err := base64.StdEncoding.DecodeString(cached); err == nil && len(b) > 0 {
+			return decodeVector(b), nil
+		}
+	}
+
+	vectors, err := client.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 0 || len(vectors[0]) == 0 {
+		return nil, errors.New("the provider returned no embedding")
+	}
+	if ttl := util.Getenv("SEARCH_QUERY_CACHE_MIN", 60); ttl > 0 {
+		kvstore.SetKey(key, base64.StdEncoding.EncodeToString(encodeVector(vectors[0])), time.Duration(ttl)*time.Minute)
+	}
+	return vectors[0], nil
+}
+
+// keywordScores scores products by the share of query words found in their name (counted double) and
+// description
+func keywordScores(db *gorm.DB, text string, limit int) (map[uint]float64, error) {
+	var terms []string
+	for _, t := range strings.Fields(strings.ToLower(text)) {
+		if len([]rune(t)) >= 2 && !slices.Contains(terms, t) && len(terms) < 8 {
+			terms = append(terms, t)
+		}
+	}
+	scores := map[uint]float64{}
+	if len(terms) == 0 {
+		return scores, nil
+	}
+
+	// The database ranks the matches before the limit so the best ones are kept rather than the newest.
+	// The rank is the score computed below times 15 per term, without its cap at 1.
+	query := db.Model(&model.Product{}).Select("id", "name", "description").Where("is_active = ?", true)
+	match := db.Where("1 = 0")
+	var rank []string
+	var rankVars []any
+	for _, t := range terms {
+		pattern := "%" + escapeLike(t) + "%"
+		match = match.Or("LOWER(name) LIKE ? ESCAPE '!'", pattern).Or("LOWER(description) LIKE ? ESCAPE '!'", pattern)
+		rank = append(rank, "CASE WHEN LOWER(name) LIKE ? ESCAPE '!' THEN 10 ELSE 0 END",
+			"CASE WHEN LOWER(description) LIKE ? ESCAPE '!' THEN 5 ELSE 0 END")
+		rankVars = append(rankVars, pattern, pattern)
+	}
+	rank = append(rank, fmt.Sprintf("CASE WHEN LOWER(name) LIKE ? ESCAPE '!' THEN %d ELSE 0 END", 3*len(terms)))
+	rankVars = append(rankVars, "%"+escapeLike(strings.ToLower(strings.TrimSpace(text)))+"%")
+
+	var products []model.Product
+	if err := query.Where(match).
+		Clauses(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "(" + strings.Join(rank, " + ") + ") DESC, id DESC",
+			Vars:               rankVars,
+			WithoutParentheses: true,
+		}}).
+		Limit(limit).
+		Find(&products).Error; err != nil {
+		return nil, err
+	}
+
+	for _, p := range products {
+		name, desc := strings.ToLower(p.Name), strings.ToLower(p.Description)
+		total := 0.0
+		for _, t := range terms {
+			if strings.Contains(name, t) {
+				total += 2
+			}
+			if strings.Contains(desc, t) {
+				total++
+			}
+		}
+		score := total / float64(3*len(terms))
+		if strings.Contains(name, strings.ToLower(strings.TrimSpace(text))) {
+			score = min(score+0.2, 1) // The whole phrase in the name
+		}
+		scores[p.ID] = score
+	}
+	return scores, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
@@ -0,0 +1,278 @@
+package semantic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Store names reported in the index status
+const (
+	StorePgvector = "pgvector"
+	StoreMemory   = "memory"
+)
+
+type hit struct {
+	ProductID uint
+	Score     float64 // Cosine similarity
+}
+
+// store keeps vectors searchable. Rows always go to product_embeddings; pgvector additionally fills the
+// embedding column so Postgres ranks them, the memory store ranks a cached copy in-process.
+type store interface {
+	name() string
+	save(db *gorm.DB, e model.ProductEmbedding, vector []float32) error
+	nearest(db *gorm.DB, modelName string, query []float32, limit int) ([]hit, error)
+	similarity(db *gorm.DB, modelName string, query []float32, productIDs []uint) (map[uint]float64, error)
+}
+
+var (
+	storeOnce sync.Once
+	current   store
+)
+
+// vectorStore picks pgvector on Postgres when the extension can be enabled, else the in-process store
+func vectorStore(db *gorm.DB) store {
+	storeOnce.Do(func() {
+		current = &memoryStore{}
+		if db.Dialector.Name() != "postgres" || !util.Getenv("SEARCH_PGVECTOR", true) {
+			return
+		}
+		err := db.Exec("CREATE EXTENSION IF NOT EXISTS vector").Error
+		if err == nil {
+			err = db.Exec("ALTER TABLE product_embeddings ADD COLUMN IF NOT EXISTS embedding vector").Error
+		}
+		if err != nil {
+			logrus.Warnf("pgvector is not available, semantic search ranks in-process: %v", err)
+			return
+		}
+		current = pgvectorStore{}
+	})
+	return current
+}
+
+func upsert(db *gorm.DB, e *model.ProductEmbedding) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"model", "dims", "vector", "content_hash", "updated_at"}),
+	}).Create(e).Error
+}
+
+// pgvectorHNSWMaxDims is the most dimensions an HNSW index on the vector type supports
+const pgvectorHNSWMaxDims = 2000
+
+// pgvectorStore keeps the embedding column untyped so a model switch does not need a migration. Each
+// dimension gets its own partial HNSW index on the column cast to vector(dims), created the first time
+// a vector of that size is saved; queries use the same cast so Postgres can use the index.
+type pgvectorStore struct{}
+
+var pgvectorIndexed sync.Map // dims -> true once the index exists
+
+func (pgvectorStore) name() string { return StorePgvector }
+
+func (pgvectorStore) save(db *gorm.DB, e model.ProductEmbedding, vector []float32) error {
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := upsert(tx, &e); err != nil {
+			return err
+		}
+		return tx.Exec("UPDATE product_embeddings SET embedding = CAST(? AS vector) WHERE product_id = ?",
+			vectorLiteral(vector), e.ProductID).Error
+	}); err != nil {
+		return err
+	}
+	ensureHNSWIndex(db, len(vector))
+	return nil
+}
+
+// ensureHNSWIndex creates the index for vectors of the given size; search still works without it, only slower
+func ensureHNSWIndex(db *gorm.DB, dims int) {
+	if dims <= 0 || dims > pgvectorHNSWMaxDims {
+		return
+	}
+	if _, ok := pgvectorIndexed.Load(dims); ok {
+		return
+	}
+	// dims is an int, so formatting it into the statement is safe; type modifiers can't be bound
+	err := db.Exec(fmt.Sprintf(`CREATE INDEX IF NOT EXISTS idx_product_embeddings_hnsw_%d ON product_embeddings
+		USING hnsw ((embedding::vector(%d)) vector_cosine_ops) WHERE dims = %d`, dims, dims, dims)).Error
+	if err != nil {
+		logrus.Warnf("Failed to create the pgvector index for %d dimensions: %v", dims, err)
+		return
+	}
+	pgvectorIndexed.Store(dims, true)
+}
+
+func (pgvectorStore) nearest(db *gorm.DB, modelName string, query []float32, limit int) ([]hit, error) {
+	var hits []hit
+	literal := vectorLiteral(query)
+	distance := fmt.Sprintf("embedding::vector(%d) <=> CAST(? AS vector(%d))", len(query), len(query))
+	err := db.Raw(fmt.Sprintf(`SELECT product_id, 1 - (%s) AS score
+		FROM product_embeddings
+		WHERE model = ? AND dims = %d AND embedding IS NOT NULL
+		ORDER BY %s
+		LIMIT ?`, distance, len(query), distance), literal, modelName, literal, limit).
+		Scan(&hits).Error
+	return hits, err
+}
+
+func (pgvectorStore) similarity(db *gorm.DB, modelName string, query []float32, productIDs []uint) (map[uint]float64, error) {
+	scores := map[uint]float64{}
+	if len(productIDs) == 0 {
+		return scores, nil
+	}
+	var hits []hit
+	if err := db.Raw(fmt.Sprintf(`SELECT product_id, 1 - (embedding::vector(%d) <=> CAST(? AS vector(%d))) AS score
+		FROM product_embeddings
+		WHERE model = ? AND dims = %d AND embedding IS NOT NULL AND product_id IN ?`, len(query), len(query), len(query)),
+		vectorLiteral(query), modelName, productIDs).
+		Scan(&hits).Error; err != nil {
+		return scores, err
+	}
+	for _, h := range hits {
+		scores[h.ProductID] = h.Score
+	}
+	return scores, nil
+}
+
+// memoryStore keeps every vector of the current model in memory and compares them one by one. It is
+// refreshed from the table every SEARCH_VECTOR_CACHE_S so other instances' updates show up.
+type memoryStore struct {
+	mu       sync.RWMutex
+	model    string
+	loadedAt time.Time
+	vectors  map[uint][]float32
+}
+
+func (m *memoryStore) name() string { return StoreMemory }
+
+func (m *memoryStore) save(db *gorm.DB, e model.ProductEmbedding, vector []float32) error {
+	if err := upsert(db, &e); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	if m.vectors != nil && m.model == e.Model {
+		m.vectors[e.ProductID] = vector
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *memoryStore) load(db *gorm.DB, modelName string) (map[uint][]float32, error) {
+	ttl := time.Duration(util.Getenv("SEARCH_VECTOR_CACHE_S", 60)) * time.Second
+	m.mu.RLock()
+	if m.vectors != nil && m.model == modelName && time.Since(m.loadedAt) < ttl {
+		defer m.mu.RUnlock()
+		return m.vectors, nil
+	}
+	m.mu.RUnlock()
+
+	loaded := map[uint][]float32{}
+	var rows []model.ProductEmbedding
+	err := db.Select("id", "product_id", "vector").
+		Where("model = ?", modelName).
+		FindInBatches(&rows, 1000, func(tx *gorm.DB, batch int) error {
+			for _, r := range rows {
+				loaded[r.ProductID] = decodeVector(r.Vector)
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.model, m.vectors, m.loadedAt = modelName, loaded, time.Now()
+	m.mu.Unlock()
+	return loaded, nil
+}
+
+func (m *memoryStore) nearest(db *gorm.DB, modelName string, query []float32, limit int) ([]hit, error) {
+	all, err := m.load(db, modelName)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	hits := make([]hit, 0, len(all))
+	for id, v := range all {
+		if len(v) == len(query) {
+			hits = append(hits, hit{ProductID: id, Score: cosine(query, v)})
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(hits, func(a, b hit) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return int(a.ProductID) - int(b.ProductID)
+	})
+	return hits[:min(limit, len(hits))], nil
+}
+
+func (m *memoryStore) similarity(db *gorm.DB, modelName string, query []float32, productIDs []uint) (map[uint]float64, error) {
+	all, err := m.load(db, modelName)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	scores := map[uint]float64{}
+	for _, id := range productIDs {
+		if v, ok := all[id]; ok && len(v) == len(query) {
+			scores[id] = cosine(query, v)
+		}
+	}
+	return scores, nil
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[i*4:], math.Float32bits(x))
+	}
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[i*4:]))
+	}
+	return v
+}
+
+// vectorLiteral formats a vector as pgvector's text input, e.g. [0.1,0.2]
+func vectorLiteral(v []float32) string {
+	parts := make([]string, len(v))
+	for i, x := range v {
+		parts[i] = strconv.FormatFloat(float64(x), 'f', -1, 32)
+	}
+	return "[" + strings.Join(parts, ",") + "]"
+}
@@ -0,0 +1,241 @@
+package transcript
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/textpdf"
+	"gorm.io/gorm"
+)
+
+// Export formats
+const (
+	FormatJSON = "json"
+	FormatText = "txt"
+	FormatPDF  = "pdf"
+)
+
+// Formats lists the supported export formats
+var Formats = []string{FormatJSON, FormatText, FormatPDF}
+
+// Transcript is a complete, self-contained record of one chat for dispute handling
+type Transcript struct {
+	ChatID       uint          `json:"chat_id"`
+	ShopID       *uint         `json:"shop_id,omitempty"`
+	ShopName     string        `json:"shop_name,omitempty"`
+	ProductID    *uint         `json:"product_id,omitempty"`
+	ProductName  string        `json:"product_name,omitempty"`
+	StartedAt    time.Time     `json:"started_at"`
+	LegalHoldAt  *time.Time    `json:"legal_hold_at,omitempty"`
+	ExportedAt   time.Time     `json:"exported_at"`
+	Participants []Participant `json:"participants"`
+	Messages     []Entry       `json:"messages"`
+}
+
+// Participant is a user in the chat at export time
+type Participant struct {
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Role   string `json:"role"`
+}
+
+// Entry is one message, including deleted and redacted ones so the record has no gaps
+type Entry struct {
+	ID         uint           `json:"id"`
+	SentAt     time.Time      `json:"sent_at"`
+	SenderID   uint           `json:"sender_id"`
+	Sender     string         `json:"sender"`
+	Kind       string         `json:"kind"`
+	Content    string         `json:"content"`
+	Payload    map[string]any `json:"payload,omitempty"`
+	Attachment string         `json:"attachment,omitempty"` // File name
+	ReplyTo    *uint          `json:"reply_to,omitempty"`
+	Edited     bool           `json:"edited,omitempty"`
+	Deleted    bool           `json:"deleted,omitempty"`
+	Flagged    bool           `json:"flagged,omitempty"`
+	RedactedAt *time.Time     `json:"redacted_at,omitempty"`
+	ReadAt     *time.Time     `json:"read_at,omitempty"`
+}
+
+// Load builds the transcript of a chat
+func Load(db *gorm.DB, chatID uint) (Transcript, error) {
+	var chat model.Chat
+	if err := db.Preload("Shop").Preload("Product").Preload("Participants.User").First(&chat, chatID).Error; err != nil {
+		return Transcript{}, err
+	}
+
+	t := Transcript{
+		ChatID:      chat.ID,
+		ShopID:      chat.ShopID,
+		StartedAt:   chat.CreatedAt,
+		LegalHoldAt: chat.LegalHoldAt,
+		ExportedAt:  time.Now(),
+	}
+	if chat.Shop != nil {
+		t.ShopName = chat.Shop.Name
+	}
+	if chat.Product != nil {
+		t.ProductID = &chat.Product.ID
+		t.ProductName = chat.Product.Name
+	}
+
+	names := map[uint]string{}
+	for _, p := range chat.Participants {
+		names[p.UserID] = displayName(p.User)
+		t.Participants = append(t.Participants, Participant{
+			UserID: p.UserID,
+			Name:   names[p.UserID],
+			Email:  string(p.User.Email),
+			Role:   p.Role,
+		})
+	}
+
+	var messages []model.Message
+	if err := db.Preload("Sender").Preload("Attachment").
+		Where("chat_id = ?", chat.ID).
+		Order("id ASC").
+		Find(&messages).Error; err != nil {
+		return t, err
+	}
+
+	t.Messages = make([]Entry, 0, len(messages))
+	for _, m := range messages {
+		e := Entry{
+			ID:         m.ID,
+			SentAt:     m.CreatedAt,
+			SenderID:   m.SenderID,
+			Sender:     names[m.SenderID],
+			Kind:       m.Kind,
+			Content:    m.Content,
+			Edited:     m.IsEdited,
+			Deleted:    m.IsDeleted,
+			Flagged:    m.IsFlagged,
+			RedactedAt: m.RedactedAt,
+			ReadAt:     m.ReadAt,
+		}
+		if e.Sender == "" { // Former participant, e.g. removed staff
+			e.Sender = displayName(m.Sender)
+		}
+		if m.Attachment != nil {
+			e.Attachment = m.Attachment.FileName
+		}
+		if m.ReplyToMessageID.Valid {
+			id := uint(m.ReplyToMessageID.Int64)
+			e.ReplyTo = &id
+		}
+		if len(m.Payload) > 0 {
+			_ = json.Unmarshal(m.Payload, &e.Payload)
+		}
+		t.Messages = append(t.Messages, e)
+	}
+	return t, nil
+}
+
+// Text renders the transcript as plain text
+func (t Transcript) Text() string {
+	var b strings.Builder
+	for _, l := range t.lines() {
+		b.WriteString(l.text)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// PDF renders the transcript as a PDF document
+func (t Transcript) PDF() []byte {
+	doc := textpdf.New(t.title())
+	for _, l := range t.lines() {
+		switch {
+		case l.heading:
+			doc.Heading(l.text)
+		case l.text == "":
+			doc.Space()
+		default:
+			doc.Text(l.text)
+		}
+	}
+	return doc.Bytes()
+}
+
+type line struct {
+	text    string
+	heading bool
+}
+
+// lines is the layout shared by the text and PDF renderings
+func (t Transcript) lines() []line {
+	const stamp = "2006-01-02 15:04:05 MST"
+
+	out := []line{{text: t.title(), heading: true}}
+	add := func(format string, args ...any) {
+		out = append(out, line{text: fmt.Sprintf(format, args...)})
+	}
+	if t.ShopName != "" {
+		add("Shop: %s", t.ShopName)
+	}
+	if t.ProductName != "" {
+		add("Product: %s (#%d)", t.ProductName, *t.ProductID)
+	}
+	add("Started: %s", t.StartedAt.Format(stamp))
+	add("Exported: %s", t.ExportedAt.Format(stamp))
+	if t.LegalHoldAt != nil {
+		add("Legal hold since: %s", t.LegalHoldAt.Format(stamp))
+	}
+	out = append(out, line{}, line{text: "Participants", heading: true})
+	for _, p := range t.Participants {
+		add("- %s <%s>, %s (user #%d)", p.Name, p.Email, p.Role, p.UserID)
+	}
+	out = append(out, line{}, line{text: fmt.Sprintf("Messages (%d)", len(t.Messages)), heading: true})
+
+	for _, m := range t.Messages {
+		var marks []string
+		if m.Kind != "" && m.Kind != model.MessageText {
+			marks = append(marks, m.Kind)
+		}
+		if m.ReplyTo != nil {
+			marks = append(marks, fmt.Sprintf("reply to #%d", *m.ReplyTo))
+		}
+		if m.Edited {
+			marks = append(marks, "edited")
+		}
+		if m.Deleted {
+			marks = append(marks, "deleted")
+		}
+		if m.Flagged {
+			marks = append(marks, "flagged")
+		}
+		if m.RedactedAt != nil {
+			marks = append(marks, "redacted")
+		}
+		header := fmt.Sprintf("[%s] #%d %s", m.SentAt.Format(stamp), m.ID, m.Sender)
+		if len(marks) > 0 {
+			header += " (" + strings.Join(marks, ", ") + ")"
+		}
+		add("%s", header)
+		add("%s", m.Content)
+		if m.Attachment != "" {
+			add("Attachment: %s", m.Attachment)
+		}
+		out = append(out, line{})
+	}
+	return out
+}
+
+func (t Transcript) title() string {
+	return fmt.Sprintf("Chat #%d transcript", t.ChatID)
+}
+
+func displayName(u model.User) string {
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if name == "" {
+		name = u.Username
+	}
+	if name == "" {
+		name = fmt.Sprintf("User #%d", u.ID)
+	}
+	return name
+}
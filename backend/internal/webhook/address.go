@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+)
+
+// ErrBlockedAddress is returned for endpoints on loopback, private, link-local or other internal addresses
+var ErrBlockedAddress = errors.New("webhook destination address is not allowed")
+
+// ValidateURL checks that an endpoint URL is an absolute http(s) URL that does not name an internal host.
+// Host names are checked again after DNS resolution when connecting.
+func ValidateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errors.New("URL must be an absolute http or https URL")
+	}
+	if allowPrivateNetworks() {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return ErrBlockedAddress
+	}
+	if ip := net.ParseIP(host); ip != nil && blockedIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// blockedIP reports whether ip is not a public unicast address
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast()
+}
+
+// dialControl runs after DNS resolution, so a public name pointing at an internal address is refused too
+func dialControl(network, address string, _ syscall.RawConn) error {
+	if allowPrivateNetworks() {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrBlockedAddress
+	}
+	if ip := net.ParseIP(host); ip == nil || blockedIP(ip) {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// allowPrivateNetworks lets endpoints on internal addresses through, for local development only
+func allowPrivateNetworks() bool {
+	return util.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false)
+}
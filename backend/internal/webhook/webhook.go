@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// TypePing is sent by the "test endpoint" action and is always delivered regardless of subscriptions
+const TypePing = "ping"
+
+// EventTypes lists the events an endpoint can subscribe to
+var EventTypes = []string{
+	events.TypeProductCreated,
+	events.TypeProductUpdated,
+	events.TypeMessageSent,
+}
+
+// Payload is the JSON body POSTed to endpoints
+type Payload struct {
+	ID        string          `json:"id"` // Event ID, stable across retries and redeliveries
+	Type      string          `json:"type"`
+	ShopID    uint            `json:"shop_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Sign returns the X-LGS-Signature header value for a request body.
+// Receivers recompute HMAC-SHA256 over "<timestamp>.<body>" with their secret and compare.
+func Sign(secret, timestamp string, body []byte) string {
+	return "sha256=" + util.SignatureGenerator([]byte(timestamp+"."+string(body)), []byte(secret))
+}
+
+// NewSecret generates a signing secret for an endpoint
+func NewSecret() (string, error) {
+	token, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	return "whsec_" + token, nil
+}
+
+// Start subscribes to domain events and runs the delivery worker.
+// Call before events.StartRelay.
+func Start(db *gorm.DB) {
+	events.Subscribe(events.TypeProductCreated, "webhook", func(env events.Envelope) error {
+		var e events.ProductCreated
+		if err := json.Unmarshal(env.Payload, &e); err != nil {
+			return err
+		}
+		return enqueue(db, env, []uint{e.Product.ShopID})
+	})
+	events.Subscribe(events.TypeProductUpdated, "webhook", func(env events.Envelope) error {
+		var e events.ProductUpdated
+		if err := json.Unmarshal(env.Payload, &e); err != nil {
+			return err
+		}
+		return enqueue(db, env, []uint{e.After.ShopID})
+	})
+	events.Subscribe(events.TypeMessageSent, "webhook", func(env events.Envelope) error {
+		var e events.MessageSent
+		if err := json.Unmarshal(env.Payload, &e); err != nil {
+			return err
+		}
+		// Notify the shop whose inbox the chat is in, or for direct chats the shop(s) of
+		// whoever took part in the conversation
+		var chat model.Chat
+		if err := db.Select("id", "shop_id").First(&chat, e.Message.ChatID).Error; err == nil && chat.ShopID != nil {
+			return enqueue(db, env, []uint{*chat.ShopID})
+		}
+		var shopIDs []uint
+		if err := db.Model(&model.Shop{}).
+			Where("user_id IN ?", []uint{e.Message.SenderID, e.Message.ReceiverID}).
+			Pluck("id", &shopIDs).Error; err != nil {
+			return err
+		}
+		return enqueue(db, env, shopIDs)
+	})
+
+	startWorker(db)
+}
+
+// enqueue creates a pending delivery for every active endpoint of the shops that subscribes to the event.
+// It is safe to call again for the same event: endpoints that already have a delivery are skipped.
+func enqueue(db *gorm.DB, env events.Envelope, shopIDs []uint) error {
+	if len(shopIDs) == 0 {
+		return nil
+	}
+
+	var endpoints []model.WebhookEndpoint
+	if err := db.Where("shop_id IN ? AND is_active = ?", shopIDs, true).Find(&endpoints).Error; err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		if !endpoint.Subscribes(env.Type) {
+			continue
+		}
+
+		var count int64
+		db.Model(&model.WebhookDelivery{}).
+			Where("endpoint_id = ? AND event_id = ? AND redelivery_of IS NULL", endpoint.ID, env.ID).
+			Count(&count)
+		if count > 0 {
+			continue
+		}
+
+		body, err := json.Marshal(Payload{
+			ID:        env.ID,
+			Type:      env.Type,
+			ShopID:    endpoint.ShopID,
+			CreatedAt: env.OccurredAt,
+			Data:      env.Payload,
+		})
+		if err != nil {
+			return fmt.Errorf("encode webhook payload: %w", err)
+		}
+
+		delivery := model.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventID:       env.ID,
+			EventType:     env.Type,
+			AggregateID:   env.AggregateID,
+			Payload:       body,
+			Status:        model.WebhookDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+		if err := db.Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+
+	wakeWorker()
+	return nil
+}
+
+// Redeliver queues a fresh copy of a past delivery with the original payload
+func Redeliver(db *gorm.DB, original model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	delivery := model.WebhookDelivery{
+		EndpointID:    original.EndpointID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		AggregateID:   original.AggregateID,
+		Payload:       original.Payload,
+		Status:        model.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+		RedeliveryOf:  &original.ID,
+	}
+	if err := db.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+	wakeWorker()
+	return &delivery, nil
+}
+
+// Ping sends a test event to the endpoint synchronously and returns the logged delivery
+func Ping(db *gorm.DB, endpoint model.WebhookEndpoint) (*model.WebhookDelivery, error) {
+	eventID := uuid.New().String()
+	body, _ := json.Marshal(Payload{
+		ID:        eventID,
+		Type:      TypePing,
+		ShopID:    endpoint.ShopID,
+		CreatedAt: time.Now(),
+		Data:      json.RawMessage(`{"message":"Webhook endpoint is reachable"}`),
+	})
+
+	delivery := model.WebhookDelivery{
+		EndpointID:    endpoint.ID,
+		EventID:       eventID,
+		EventType:     TypePing,
+		Payload:       body,
+		Status:        model.WebhookDeliverySending,
+		NextAttemptAt: time.Now(),
+	}
+	if err := db.Create(&delivery).Error; err != nil {
+		return nil, err
+	}
+
+	// A ping is a one-off check: it neither retries nor counts toward auto-disable
+	result := send(endpoint, delivery)
+	delivery.Attempts = 1
+	delivery.ResponseCode = result.code
+	delivery.DurationMs = result.duration.Milliseconds()
+	delivery.Status = model.WebhookDeliveryFailed
+	if result.err == nil {
+		now := time.Now()
+		delivery.Status = model.WebhookDeliverySuccess
+		delivery.DeliveredAt = &now
+	} else {
+		delivery.Error = result.err.Error()
+	}
+	db.Save(&delivery)
+	return &delivery, nil
+}
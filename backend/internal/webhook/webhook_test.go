@@ -0,0 +1,380 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/events"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// receiver is a local endpoint that records requests and answers with the configured status
+type receiver struct {
+	mu       sync.Mutex
+	requests []received
+	status   int
+	block    bool // Hold the request until the client gives up
+}
+
+type received struct {
+	header http.Header
+	body   []byte
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	rc.requests = append(rc.requests, received{header: r.Header.Clone(), body: body})
+	status, block := rc.status, rc.block
+	rc.mu.Unlock()
+	if block {
+		<-r.Context().Done()
+		return
+	}
+	w.WriteHeader(status)
+	w.Write([]byte("internal details that must not be stored"))
+}
+
+func (rc *receiver) count() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.requests)
+}
+
+func setup(t *testing.T, status int) (*gorm.DB, *receiver, model.WebhookEndpoint) {
+	t.Helper()
+	// httptest listens on loopback
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "true")
+
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.Shop{}, &model.WebhookEndpoint{}, &model.WebhookDelivery{}); err != nil {
+		t.Fatal(err)
+	}
+
+	rc := &receiver{status: status}
+	server := httptest.NewServer(rc)
+	t.Cleanup(server.Close)
+
+	shop := model.Shop{UserID: 1, Name: "Toko", Slug: "toko-" + t.Name()}
+	if err := db.Create(&shop).Error; err != nil {
+		t.Fatal(err)
+	}
+	endpoint := model.WebhookEndpoint{
+		ShopID:     shop.ID,
+		URL:        server.URL + "/hooks",
+		Secret:     "whsec_test",
+		EventTypes: []string{events.TypeProductCreated},
+		IsActive:   true,
+	}
+	if err := db.Create(&endpoint).Error; err != nil {
+		t.Fatal(err)
+	}
+	return db, rc, endpoint
+}
+
+func queue(t *testing.T, db *gorm.DB, endpoint model.WebhookEndpoint, eventID string) model.WebhookDelivery {
+	t.Helper()
+	env := events.Envelope{
+		ID:         eventID,
+		Type:       events.TypeProductCreated,
+		OccurredAt: time.Now(),
+		Payload:    []byte(`{"product":{"id":7}}`),
+	}
+	if err := enqueue(db, env, []uint{endpoint.ShopID}); err != nil {
+		t.Fatal(err)
+	}
+	var delivery model.WebhookDelivery
+	if err := db.Where("endpoint_id = ? AND event_id = ?", endpoint.ID, eventID).Order("id DESC").First(&delivery).Error; err != nil {
+		t.Fatal(err)
+	}
+	return delivery
+}
+
+func reload(t *testing.T, db *gorm.DB, delivery model.WebhookDelivery) model.WebhookDelivery {
+	t.Helper()
+	var fresh model.WebhookDelivery
+	if err := db.First(&fresh, delivery.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	return fresh
+}
+
+func TestDeliverySignature(t *testing.T) {
+	db, rc, endpoint := setup(t, http.StatusOK)
+	delivery := queue(t, db, endpoint, "evt-1")
+
+	processDue(db)
+
+	if rc.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", rc.count())
+	}
+	req := rc.requests[0]
+	for header, want := range map[string]string{
+		"Content-Type":   "application/json",
+		"X-LGS-Event":    events.TypeProductCreated,
+		"X-LGS-Event-ID": "evt-1",
+		"X-LGS-Delivery": fmt.Sprint(delivery.ID),
+	} {
+		if got := req.header.Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	timestamp := req.header.Get("X-LGS-Timestamp")
+	mac := hmac.New(sha256.New, []byte(endpoint.Secret))
+	mac.Write([]byte(timestamp + "." + string(req.body)))
+	want := "sha256=" + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if got := req.header.Get("X-LGS-Signature"); got != want {
+		t.Errorf("X-LGS-Signature = %q, want %q", got, want)
+	}
+	if !strings.Contains(string(req.body), `"id":"evt-1"`) {
+		t.Errorf("payload %s does not carry the event ID", req.body)
+	}
+
+	delivery = reload(t, db, delivery)
+	if delivery.Status != model.WebhookDeliverySuccess || delivery.ResponseCode != http.StatusOK || delivery.DeliveredAt == nil {
+		t.Errorf("delivery = %s/%d, want success/200 with delivered_at", delivery.Status, delivery.ResponseCode)
+	}
+}
+
+func TestDeliveryRetriesWithBackoffOnServerError(t *testing.T) {
+	db, rc, endpoint := setup(t, http.StatusInternalServerError)
+	delivery := queue(t, db, endpoint, "evt-1")
+
+	before := time.Now()
+	processDue(db)
+
+	delivery = reload(t, db, delivery)
+	if delivery.Status != model.WebhookDeliveryPending || delivery.Attempts != 1 || delivery.ResponseCode != 500 {
+		t.Fatalf("delivery = %s, attempts %d, code %d; want pending, 1, 500", delivery.Status, delivery.Attempts, delivery.ResponseCode)
+	}
+	if delivery.Error != "endpoint responded with HTTP 500" {
+		t.Errorf("error = %q", delivery.Error)
+	}
+	if wait := delivery.NextAttemptAt.Sub(before); wait < 29*time.Second || wait > 31*time.Second {
+		t.Errorf("next attempt in %v, want about 30s", wait)
+	}
+
+	// Not due yet
+	processDue(db)
+	if rc.count() != 1 {
+		t.Fatalf("retried before the backoff elapsed: %d requests", rc.count())
+	}
+
+	db.Model(&delivery).Update("next_attempt_at", time.Now().Add(-time.Second))
+	rc.status = http.StatusNoContent
+	processDue(db)
+	if delivery = reload(t, db, delivery); delivery.Status != model.WebhookDeliverySuccess || delivery.Attempts != 2 {
+		t.Errorf("after retry: %s with %d attempts, want success with 2", delivery.Status, delivery.Attempts)
+	}
+	if rc.count() != 2 {
+		t.Errorf("receiver got %d requests, want 2", rc.count())
+	}
+}
+
+func TestDeliveryTimeout(t *testing.T) {
+	t.Setenv("WEBHOOK_TIMEOUT_S", "1")
+	db, rc, endpoint := setup(t, http.StatusOK)
+	rc.block = true
+	delivery := queue(t, db, endpoint, "evt-1")
+
+	processDue(db)
+
+	delivery = reload(t, db, delivery)
+	if delivery.Status != model.WebhookDeliveryPending || delivery.Attempts != 1 {
+		t.Fatalf("delivery = %s with %d attempts, want pending with 1", delivery.Status, delivery.Attempts)
+	}
+	if delivery.Error != "request timed out" {
+		t.Errorf("error = %q, want a timeout", delivery.Error)
+	}
+	if !delivery.NextAttemptAt.After(time.Now()) {
+		t.Error("timed out delivery was not scheduled for a retry")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		10: 512 * 30 * time.Second,
+		11: 6 * time.Hour,
+		40: 6 * time.Hour,
+	} {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestEndpointDisabledAfterRepeatedFailures(t *testing.T) {
+	t.Setenv("WEBHOOK_MAX_CONSECUTIVE_FAILURES", "3")
+	db, rc, endpoint := setup(t, http.StatusBadGateway)
+
+	for i := range 3 {
+		queue(t, db, endpoint, fmt.Sprintf("evt-%d", i))
+		processDue(db)
+	}
+
+	db.First(&endpoint, endpoint.ID)
+	if endpoint.IsActive || endpoint.DisabledAt == nil || endpoint.FailureCount != 3 {
+		t.Fatalf("endpoint active=%v failures=%d, want disabled after 3", endpoint.IsActive, endpoint.FailureCount)
+	}
+	if !strings.Contains(endpoint.DisabledReason, "3 consecutive") {
+		t.Errorf("disabled reason = %q", endpoint.DisabledReason)
+	}
+
+	// Pending retries of a disabled endpoint are dropped without a request
+	db.Model(&model.WebhookDelivery{}).Where("1 = 1").Update("next_attempt_at", time.Now().Add(-time.Second))
+	processDue(db)
+	if rc.count() != 3 {
+		t.Errorf("receiver got %d requests, want 3", rc.count())
+	}
+	var pending int64
+	db.Model(&model.WebhookDelivery{}).Where("status = ?", model.WebhookDeliveryPending).Count(&pending)
+	if pending != 0 {
+		t.Errorf("%d deliveries still pending for a disabled endpoint", pending)
+	}
+}
+
+func TestSuccessResetsFailureCount(t *testing.T) {
+	t.Setenv("WEBHOOK_MAX_CONSECUTIVE_FAILURES", "3")
+	db, rc, endpoint := setup(t, http.StatusServiceUnavailable)
+
+	queue(t, db, endpoint, "evt-1")
+	queue(t, db, endpoint, "evt-2")
+	processDue(db)
+	rc.status = http.StatusOK
+	queue(t, db, endpoint, "evt-3")
+	processDue(db)
+
+	db.First(&endpoint, endpoint.ID)
+	if !endpoint.IsActive || endpoint.FailureCount != 0 {
+		t.Errorf("endpoint active=%v failures=%d, want active with 0", endpoint.IsActive, endpoint.FailureCount)
+	}
+}
+
+func TestManualRedelivery(t *testing.T) {
+	db, rc, endpoint := setup(t, http.StatusOK)
+	original := queue(t, db, endpoint, "evt-1")
+	processDue(db)
+
+	// Enqueueing the same event again is a no-op; a redelivery is explicit
+	queue(t, db, endpoint, "evt-1")
+	processDue(db)
+	if rc.count() != 1 {
+		t.Fatalf("duplicate event was delivered again: %d requests", rc.count())
+	}
+
+	original = reload(t, db, original)
+	redelivery, err := Redeliver(db, original)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if redelivery.RedeliveryOf == nil || *redelivery.RedeliveryOf != original.ID {
+		t.Errorf("redelivery_of = %v, want %d", redelivery.RedeliveryOf, original.ID)
+	}
+	processDue(db)
+
+	if rc.count() != 2 {
+		t.Fatalf("receiver got %d requests, want 2", rc.count())
+	}
+	first, second := rc.requests[0], rc.requests[1]
+	if string(first.body) != string(second.body) || second.header.Get("X-LGS-Event-ID") != "evt-1" {
+		t.Error("redelivery did not resend the original payload and event ID")
+	}
+	if second.header.Get("X-LGS-Delivery") != fmt.Sprint(redelivery.ID) {
+		t.Errorf("X-LGS-Delivery = %s, want %d", second.header.Get("X-LGS-Delivery"), redelivery.ID)
+	}
+	if got := reload(t, db, *redelivery); got.Status != model.WebhookDeliverySuccess {
+		t.Errorf("redelivery status = %s, want success", got.Status)
+	}
+}
+
+func TestPingKeepsNoResponseBody(t *testing.T) {
+	db, rc, endpoint := setup(t, http.StatusTeapot)
+
+	delivery, err := Ping(db, endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.count() != 1 || delivery.Status != model.WebhookDeliveryFailed || delivery.ResponseCode != http.StatusTeapot {
+		t.Fatalf("ping = %s/%d after %d requests", delivery.Status, delivery.ResponseCode, rc.count())
+	}
+	var stored string
+	db.Raw("SELECT COALESCE(error, '') FROM webhook_deliveries WHERE id = ?", delivery.ID).Scan(&stored)
+	if strings.Contains(stored, "internal details") {
+		t.Errorf("stored error leaks the response body: %q", stored)
+	}
+}
+
+func TestInternalAddressesAreBlocked(t *testing.T) {
+	for _, raw := range []string{
+		"http://localhost/hook",
+		"http://api.localhost/hook",
+		"http://127.0.0.1:6379/",
+		"http://10.1.2.3/hook",
+		"http://192.168.1.10/hook",
+		"http://172.16.0.1/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://0.0.0.0/hook",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://224.0.0.1/hook",
+	} {
+		if err := ValidateURL(raw); err != ErrBlockedAddress {
+			t.Errorf("ValidateURL(%s) = %v, want ErrBlockedAddress", raw, err)
+		}
+	}
+	for _, raw := range []string{"https://example.com/hook", "http://93.184.216.34/hook"} {
+		if err := ValidateURL(raw); err != nil {
+			t.Errorf("ValidateURL(%s) = %v, want nil", raw, err)
+		}
+	}
+	for _, raw := range []string{"ftp://example.com/", "/relative", "http://"} {
+		if err := ValidateURL(raw); err == nil || err == ErrBlockedAddress {
+			t.Errorf("ValidateURL(%s) = %v, want a URL error", raw, err)
+		}
+	}
+}
+
+func TestDialerRefusesInternalAddresses(t *testing.T) {
+	_, rc, endpoint := setup(t, http.StatusOK)
+	t.Setenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false")
+
+	for address, want := range map[string]error{
+		"127.0.0.1:80":         ErrBlockedAddress,
+		"[::ffff:10.0.0.1]:80": ErrBlockedAddress,
+		"169.254.169.254:80":   ErrBlockedAddress,
+		"93.184.216.34:443":    nil,
+	} {
+		if err := dialControl("tcp", address, nil); err != want {
+			t.Errorf("dialControl(%s) = %v, want %v", address, err, want)
+		}
+	}
+
+	// The check runs on the resolved address, so it holds even when URL validation is bypassed,
+	// e.g. by a public name that resolves to an internal address
+	req, _ := http.NewRequest(http.MethodPost, endpoint.URL, strings.NewReader(`{}`))
+	_, err := client.Do(req)
+	if requestError(err) != ErrBlockedAddress || rc.count() != 0 {
+		t.Errorf("request to loopback = %v after %d requests, want ErrBlockedAddress", err, rc.count())
+	}
+	if result := send(endpoint, model.WebhookDelivery{Payload: []byte(`{}`)}); result.err != ErrBlockedAddress {
+		t.Errorf("send = %v, want ErrBlockedAddress", result.err)
+	}
+}
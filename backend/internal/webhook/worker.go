@@ -0,0 +1,244 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const maxResponseBody = 2048
+
+var (
+	wake   = make(chan struct{}, 1)
+	client = &http.Client{
+		// Connect directly, without an environment proxy, so every destination address goes through dialControl
+		Transport: &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: 10 * time.Second, Control: dialControl}).DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		// Report redirects as-is instead of following them to another host
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+)
+
+func wakeWorker() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+func startWorker(db *gorm.DB) {
+	interval := time.Duration(util.Getenv("WEBHOOK_POLL_INTERVAL_S", 5)) * time.Second
+
+	go func() {
+		// Deliveries claimed by a previous process that died mid-request
+		db.Model(&model.WebhookDelivery{}).
+			Where("status = ? AND updated_at < ?", model.WebhookDeliverySending, time.Now().Add(-10*time.Minute)).
+			Update("status", model.WebhookDeliveryPending)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+			case <-wake:
+			}
+			processDue(db)
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			pruneDeliveries(db)
+			<-ticker.C
+		}
+	}()
+}
+
+// pruneDeliveries deletes finished deliveries older than WEBHOOK_DELIVERY_RETENTION_DAYS (0 keeps them);
+// each one holds a full copy of its event
+func pruneDeliveries(db *gorm.DB) {
+	days := util.Getenv("WEBHOOK_DELIVERY_RETENTION_DAYS", 30)
+	if days <= 0 {
+		return
+	}
+	res := db.Where("status IN ? AND created_at < ?",
+		[]string{model.WebhookDeliverySuccess, model.WebhookDeliveryFailed}, time.Now().AddDate(0, 0, -days)).
+		Delete(&model.WebhookDelivery{})
+	if res.Error != nil {
+		logrus.Errorf("Failed to prune webhook deliveries: %v", res.Error)
+	} else if res.RowsAffected > 0 {
+		logrus.Infof("Pruned %d webhook deliveries", res.RowsAffected)
+	}
+}
+
+func processDue(db *gorm.DB) {
+	var due []model.WebhookDelivery
+	if err := db.Where("status = ? AND next_attempt_at <= ?", model.WebhookDeliveryPending, time.Now()).
+		Order("next_attempt_at ASC").
+		Limit(50).
+		Find(&due).Error; err != nil {
+		return // Table may not be migrated yet
+	}
+
+	for _, delivery := range due {
+		// Claim the row so concurrent workers don't send it twice
+		res := db.Model(&model.WebhookDelivery{}).
+			Where("id = ? AND status = ?", delivery.ID, model.WebhookDeliveryPending).
+			Update("status", model.WebhookDeliverySending)
+		if res.Error != nil || res.RowsAffected == 0 {
+			continue
+		}
+		deliver(db, delivery)
+	}
+}
+
+func deliver(db *gorm.DB, delivery model.WebhookDelivery) {
+	var endpoint model.WebhookEndpoint
+	if err := db.First(&endpoint, delivery.EndpointID).Error; err != nil || !endpoint.IsActive {
+		db.Model(&delivery).Updates(map[string]interface{}{
+			"status": model.WebhookDeliveryFailed,
+			"error":  "endpoint deleted or disabled",
+		})
+		return
+	}
+
+	result := send(endpoint, delivery)
+	delivery.Attempts++
+
+	updates := map[string]interface{}{
+		"attempts":      delivery.Attempts,
+		"response_code": result.code,
+		"duration_ms":   result.duration.Milliseconds(),
+	}
+
+	if result.err == nil {
+		updates["status"] = model.WebhookDeliverySuccess
+		updates["delivered_at"] = time.Now()
+		updates["error"] = ""
+		db.Model(&delivery).Updates(updates)
+		if endpoint.FailureCount > 0 {
+			db.Model(&endpoint).Update("failure_count", 0)
+		}
+		return
+	}
+
+	maxAttempts := util.Getenv("WEBHOOK_MAX_ATTEMPTS", 8)
+	updates["error"] = result.err.Error()
+	updates["status"] = model.WebhookDeliveryPending
+	updates["next_attempt_at"] = time.Now().Add(backoff(delivery.Attempts))
+	if delivery.Attempts >= maxAttempts {
+		updates["status"] = model.WebhookDeliveryFailed
+	}
+	db.Model(&delivery).Updates(updates)
+	logrus.Warnf("Webhook delivery %d to endpoint %d failed (attempt %d/%d): %v", delivery.ID, endpoint.ID, delivery.Attempts, maxAttempts, result.err)
+
+	recordFailure(db, endpoint)
+}
+
+// recordFailure bumps the endpoint's consecutive failure count and disables it past the threshold
+func recordFailure(db *gorm.DB, endpoint model.WebhookEndpoint) {
+	db.Model(&endpoint).Update("failure_count", gorm.Expr("failure_count + 1"))
+	db.Select("failure_count", "is_active").First(&endpoint, endpoint.ID)
+
+	threshold := util.Getenv("WEBHOOK_MAX_CONSECUTIVE_FAILURES", 20)
+	if !endpoint.IsActive || endpoint.FailureCount < threshold {
+		return
+	}
+
+	now := time.Now()
+	reason := fmt.Sprintf("Disabled after %d consecutive failed deliveries", endpoint.FailureCount)
+	db.Model(&endpoint).Updates(map[string]interface{}{
+		"is_active":       false,
+		"disabled_at":     now,
+		"disabled_reason": reason,
+	})
+	logrus.Warnf("Webhook endpoint %d disabled: %s", endpoint.ID, reason)
+}
+
+type sendResult struct {
+	code     int
+	duration time.Duration
+	err      error // Short and safe to show to the seller
+}
+
+// send POSTs the delivery payload to the endpoint; any non-2xx response is an error.
+// The response body is never kept: sellers only see the status code.
+func send(endpoint model.WebhookEndpoint, delivery model.WebhookDelivery) sendResult {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	if err := ValidateURL(endpoint.URL); err != nil {
+		return sendResult{err: err}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(util.Getenv("WEBHOOK_TIMEOUT_S", 10))*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return sendResult{err: errors.New("invalid endpoint URL")}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "LGS-Webhook/1.0")
+	req.Header.Set("X-LGS-Event", delivery.EventType)
+	req.Header.Set("X-LGS-Event-ID", delivery.EventID)
+	req.Header.Set("X-LGS-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-LGS-Timestamp", timestamp)
+	req.Header.Set("X-LGS-Signature", Sign(endpoint.Secret, timestamp, delivery.Payload))
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	result := sendResult{duration: time.Since(start)}
+	if err != nil {
+		logrus.Debugf("Webhook request to endpoint %d failed: %v", endpoint.ID, err)
+		result.err = requestError(err)
+		return result
+	}
+	defer resp.Body.Close()
+
+	// Drain a little so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	result.code = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		result.err = fmt.Errorf("endpoint responded with HTTP %d", resp.StatusCode)
+	}
+	return result
+}
+
+// requestError reduces a transport error to a short reason that reveals nothing about the network
+func requestError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrBlockedAddress):
+		return ErrBlockedAddress
+	case errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()):
+		return errors.New("request timed out")
+	default:
+		return errors.New("could not connect to the endpoint")
+	}
+}
+
+// backoff returns the delay before the next attempt: 30s, 1m, 2m, 4m ... capped at 6h
+func backoff(attempts int) time.Duration {
+	d := 30 * time.Second << min(attempts-1, 16)
+	if d > 6*time.Hour {
+		return 6 * time.Hour
+	}
+	return d
+}
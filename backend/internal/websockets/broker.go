@@ -0,0 +1,163 @@
+package websockets
+
+import (
+	"sync"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// InstanceID identifies this process in the cluster; brokers use it to skip their own messages
+var InstanceID = uuid.New().String()
+
+// BrokerMessage is a frame fanned out to other instances
+type BrokerMessage struct {
+	Origin      string `json:"origin"`
+	UserID      uint   `json:"user_id,omitempty"` // 0 = broadcast to everyone
+	MessageType int    `json:"message_type"`
+	Data        []byte `json:"data"`
+}
+
+// Broker fans messages out to the other instances serving websocket clients.
+// Messages are always delivered to local connections first; the broker only covers the rest of the cluster.
+type Broker interface {
+	Name() string
+	Publish(msg BrokerMessage) error
+	// Subscribe starts receiving messages published by other instances
+	Subscribe(handler func(msg BrokerMessage)) error
+	Close() error
+}
+
+// Presence tracks which users have at least one connection anywhere in the cluster
+type Presence interface {
+	// SetOnline records that this instance gained its first (online=true) or lost its last connection for a user
+	SetOnline(userID uint, online bool)
+	// Refresh extends this instance's claim on users that are still connected
+	Refresh(userIDs []uint)
+	IsOnline(userID uint) bool
+	OnlineUsers() []uint
+}
+
+var (
+	broker      Broker   = localBroker{}
+	presence    Presence = localPresence{}
+	brokerMutex sync.RWMutex
+)
+
+// InitBroker selects the fan-out backend from WS_BROKER ("local" or "redis").
+// With "redis" every instance must share the same Redis server.
+func InitBroker(rdb *redis.Client) {
+	switch util.Getenv("WS_BROKER", "local") {
+	case "redis":
+		if rdb == nil {
+			logrus.Warn("WS_BROKER=redis but Redis is not configured, using local websocket broker")
+			return
+		}
+		ttl := time.Duration(util.Getenv("WS_PRESENCE_TTL_S", 60)) * time.Second
+		UseBroker(NewRedisBroker(rdb, InstanceID), NewRedisPresence(rdb, InstanceID, ttl))
+		go refreshPresence(ttl / 3)
+	default:
+		UseBroker(localBroker{}, localPresence{})
+	}
+}
+
+// UseBroker starts consuming remote messages from b and swaps it in with its presence registry.
+// If the subscription fails the current broker is kept.
+func UseBroker(b Broker, p Presence) {
+	if err := b.Subscribe(deliverRemote); err != nil {
+		current, _ := currentBroker()
+		logrus.Errorf("Failed to subscribe websocket broker %s, keeping %s: %v", b.Name(), current.Name(), err)
+		return
+	}
+
+	brokerMutex.Lock()
+	old := broker
+	broker, presence = b, p
+	brokerMutex.Unlock()
+
+	if old != b {
+		old.Close()
+	}
+
+	// Claim users that connected before the switch
+	Mutex.RLock()
+	users := onlineUsers()
+	Mutex.RUnlock()
+	p.Refresh(users)
+
+	logrus.Printf("Websocket broker: %s (instance %s)", b.Name(), InstanceID)
+}
+
+func currentBroker() (Broker, Presence) {
+	brokerMutex.RLock()
+	defer brokerMutex.RUnlock()
+	return broker, presence
+}
+
+// publish forwards a frame to the other instances
+func publish(userID uint, messageType int, data []byte) {
+	b, _ := currentBroker()
+	err := b.Publish(BrokerMessage{
+		Origin:      InstanceID,
+		UserID:      userID,
+		MessageType: messageType,
+		Data:        data,
+	})
+	if err != nil {
+		logrus.Errorf("Websocket broker %s publish failed: %v", b.Name(), err)
+	}
+}
+
+// deliverRemote hands a message from another instance to the local connections
+func deliverRemote(msg BrokerMessage) {
+	if msg.Origin == InstanceID {
+		return
+	}
+	if msg.UserID == 0 {
+		broadcastLocal(msg.MessageType, msg.Data)
+		return
+	}
+	sendToUserLocal(msg.MessageType, msg.Data, msg.UserID)
+}
+
+func refreshPresence(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		Mutex.RLock()
+		users := onlineUsers()
+		Mutex.RUnlock()
+
+		_, p := currentBroker()
+		p.Refresh(users)
+	}
+}
+
+// localBroker is used for single-instance deployments: there is nobody else to fan out to
+type localBroker struct{}
+
+func (localBroker) Name() string                            { return "local" }
+func (localBroker) Publish(BrokerMessage) error             { return nil }
+func (localBroker) Subscribe(func(msg BrokerMessage)) error { return nil }
+func (localBroker) Close() error                            { return nil }
+
+// localPresence answers from this process's connection maps
+type localPresence struct{}
+
+func (localPresence) SetOnline(uint, bool) {}
+func (localPresence) Refresh([]uint)       {}
+
+func (localPresence) IsOnline(userID uint) bool {
+	Mutex.RLock()
+	defer Mutex.RUnlock()
+	return len(UserConnections[userID]) > 0
+}
+
+func (localPresence) OnlineUsers() []uint {
+	Mutex.RLock()
+	defer Mutex.RUnlock()
+	return onlineUsers()
+}
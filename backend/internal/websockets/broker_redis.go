@@ -0,0 +1,170 @@
+package websockets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	redisChannel        = "ws:fanout"
+	redisOnlineKey      = "ws:online"
+	redisUserKeyPrefix  = "ws:presence:"
+	redisCommandTimeout = 3 * time.Second
+)
+
+// RedisBroker fans messages out over Redis pub/sub
+type RedisBroker struct {
+	rdb        *redis.Client
+	instanceID string
+	pubsub     *redis.PubSub
+}
+
+// NewRedisBroker creates a broker on the given client; all instances must use the same Redis
+func NewRedisBroker(rdb *redis.Client, instanceID string) *RedisBroker {
+	return &RedisBroker{rdb: rdb, instanceID: instanceID}
+}
+
+func (b *RedisBroker) Name() string { return "redis" }
+
+func (b *RedisBroker) Publish(msg BrokerMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+	return b.rdb.Publish(ctx, redisChannel, payload).Err()
+}
+
+// Subscribe listens on the fan-out channel; go-redis reconnects the subscription on its own
+func (b *RedisBroker) Subscribe(handler func(msg BrokerMessage)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+
+	pubsub := b.rdb.Subscribe(context.Background(), redisChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("subscribe %s: %w", redisChannel, err)
+	}
+	b.pubsub = pubsub
+
+	go func() {
+		for m := range pubsub.Channel() {
+			var msg BrokerMessage
+			if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+				logrus.Warnf("Dropping malformed websocket broker message: %v", err)
+				continue
+			}
+			handler(msg)
+		}
+	}()
+	return nil
+}
+
+func (b *RedisBroker) Close() error {
+	if b.pubsub == nil {
+		return nil
+	}
+	return b.pubsub.Close()
+}
+
+// RedisPresence keeps cluster-wide presence in Redis.
+// Each user has a sorted set of instance IDs scored by claim expiry, and ws:online holds every online user
+// scored by their latest expiry, so a crashed instance's users drop off once its claims lapse.
+type RedisPresence struct {
+	rdb        *redis.Client
+	instanceID string
+	ttl        time.Duration
+}
+
+// NewRedisPresence creates a presence registry whose claims expire after ttl unless refreshed
+func NewRedisPresence(rdb *redis.Client, instanceID string, ttl time.Duration) *RedisPresence {
+	return &RedisPresence{rdb: rdb, instanceID: instanceID, ttl: ttl}
+}
+
+func (p *RedisPresence) SetOnline(userID uint, online bool) {
+	if online {
+		p.Refresh([]uint{userID})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+
+	key := p.userKey(userID)
+	member := strconv.FormatUint(uint64(userID), 10)
+	p.rdb.ZRem(ctx, key, p.instanceID)
+	p.prune(ctx, key)
+	if remaining, err := p.rdb.ZCard(ctx, key).Result(); err == nil && remaining == 0 {
+		p.rdb.ZRem(ctx, redisOnlineKey, member)
+	}
+}
+
+func (p *RedisPresence) Refresh(userIDs []uint) {
+	if len(userIDs) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+
+	expiry := float64(time.Now().Add(p.ttl).Unix())
+	pipe := p.rdb.Pipeline()
+	for _, userID := range userIDs {
+		key := p.userKey(userID)
+		pipe.ZAdd(ctx, key, &redis.Z{Score: expiry, Member: p.instanceID})
+		pipe.Expire(ctx, key, p.ttl*2)
+		pipe.ZAdd(ctx, redisOnlineKey, &redis.Z{Score: expiry, Member: strconv.FormatUint(uint64(userID), 10)})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		logrus.Errorf("Failed to refresh websocket presence: %v", err)
+	}
+}
+
+func (p *RedisPresence) IsOnline(userID uint) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+
+	key := p.userKey(userID)
+	p.prune(ctx, key)
+	count, err := p.rdb.ZCard(ctx, key).Result()
+	if err != nil {
+		return localPresence{}.IsOnline(userID)
+	}
+	return count > 0
+}
+
+func (p *RedisPresence) OnlineUsers() []uint {
+	ctx, cancel := context.WithTimeout(context.Background(), redisCommandTimeout)
+	defer cancel()
+
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	p.rdb.ZRemRangeByScore(ctx, redisOnlineKey, "-inf", "("+now)
+	members, err := p.rdb.ZRange(ctx, redisOnlineKey, 0, -1).Result()
+	if err != nil {
+		return localPresence{}.OnlineUsers()
+	}
+
+	userIDs := make([]uint, 0, len(members))
+	for _, m := range members {
+		if id, err := strconv.ParseUint(m, 10, 32); err == nil {
+			userIDs = append(userIDs, uint(id))
+		}
+	}
+	return userIDs
+}
+
+// prune drops instance claims that were not refreshed in time
+func (p *RedisPresence) prune(ctx context.Context, key string) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	p.rdb.ZRemRangeByScore(ctx, key, "-inf", "("+now)
+}
+
+func (p *RedisPresence) userKey(userID uint) string {
+	return redisUserKeyPrefix + strconv.FormatUint(uint64(userID), 10)
+}
@@ -0,0 +1,196 @@
+package websockets
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
+)
+
+func newRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { rdb.Close() })
+	return mr, rdb
+}
+
+// connect registers a local connection for the user without a socket; frames queue on its send channel
+func connect(t *testing.T, userID uint, connectionID string) *Client {
+	t.Helper()
+	c := newClient(userID, connectionID, "", nil)
+	Mutex.Lock()
+	Clients[connectionID] = c
+	UserConnections[userID] = append(UserConnections[userID], connectionID)
+	Mutex.Unlock()
+	t.Cleanup(func() {
+		Mutex.Lock()
+		delete(Clients, connectionID)
+		delete(UserConnections, userID)
+		Mutex.Unlock()
+	})
+	return c
+}
+
+func useLocalBroker(t *testing.T) {
+	t.Cleanup(func() { UseBroker(localBroker{}, localPresence{}) })
+}
+
+func receive(t *testing.T, c *Client) string {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		return string(msg.data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no frame received")
+		return ""
+	}
+}
+
+func assertNothingReceived(t *testing.T, c *Client) {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		t.Fatalf("unexpected frame %s", msg.data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestRedisBrokerFansOutAcrossInstances(t *testing.T) {
+	_, rdb := newRedis(t)
+	useLocalBroker(t)
+	UseBroker(NewRedisBroker(rdb, InstanceID), NewRedisPresence(rdb, InstanceID, time.Minute))
+	if b, _ := currentBroker(); b.Name() != "redis" {
+		t.Fatalf("broker = %s, want redis", b.Name())
+	}
+
+	// A second instance on the same Redis
+	other := NewRedisBroker(rdb, "instance-b")
+	received := make(chan BrokerMessage, 4)
+	if err := other.Subscribe(func(msg BrokerMessage) { received <- msg }); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { other.Close() })
+
+	local := connect(t, 7, "conn-7")
+
+	// A message for a user connected elsewhere reaches the other instance
+	SendMessageToUser(websocket.TextMessage, "to-remote", 9)
+	select {
+	case msg := <-received:
+		if msg.Origin != InstanceID || msg.UserID != 9 || string(msg.Data) != "to-remote" {
+			t.Errorf("other instance got %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("other instance received nothing")
+	}
+
+	// The other instance's messages reach our local connections
+	if err := other.Publish(BrokerMessage{Origin: "instance-b", UserID: 7, MessageType: websocket.TextMessage, Data: []byte("from-remote")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, local); got != "from-remote" {
+		t.Errorf("local connection got %q", got)
+	}
+	if err := other.Publish(BrokerMessage{Origin: "instance-b", MessageType: websocket.TextMessage, Data: []byte("broadcast")}); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, local); got != "broadcast" {
+		t.Errorf("local connection got %q for a broadcast", got)
+	}
+
+	// A local send is delivered once, not again when our own message comes back from Redis
+	SendMessageToUser(websocket.TextMessage, "local", 7)
+	if got := receive(t, local); got != "local" {
+		t.Errorf("local connection got %q", got)
+	}
+	<-received
+	assertNothingReceived(t, local)
+}
+
+func TestRedisPresenceAcrossInstances(t *testing.T) {
+	_, rdb := newRedis(t)
+	a := NewRedisPresence(rdb, "instance-a", time.Minute)
+	b := NewRedisPresence(rdb, "instance-b", time.Minute)
+
+	a.SetOnline(1, true)
+	if !b.IsOnline(1) || !slices.Contains(b.OnlineUsers(), 1) {
+		t.Fatal("user connected on instance a is not online for instance b")
+	}
+
+	// Online while any instance still holds a connection
+	b.SetOnline(1, true)
+	a.SetOnline(1, false)
+	if !a.IsOnline(1) {
+		t.Error("user went offline while still connected to instance b")
+	}
+	b.SetOnline(1, false)
+	if a.IsOnline(1) || slices.Contains(a.OnlineUsers(), 1) {
+		t.Error("user still online after disconnecting everywhere")
+	}
+}
+
+func TestRedisPresenceExpiresWithoutRefresh(t *testing.T) {
+	mr, rdb := newRedis(t)
+	crashed := NewRedisPresence(rdb, "instance-a", time.Second)
+	alive := NewRedisPresence(rdb, "instance-b", time.Minute)
+
+	crashed.SetOnline(1, true)
+	alive.SetOnline(2, true)
+	if !alive.IsOnline(1) {
+		t.Fatal("user not online right after connecting")
+	}
+
+	// Claims are scored in whole seconds; once instance a stops refreshing its users drop off
+	time.Sleep(2100 * time.Millisecond)
+	if alive.IsOnline(1) {
+		t.Error("user of an instance that stopped refreshing is still online")
+	}
+	if got := alive.OnlineUsers(); !slices.Equal(got, []uint{2}) {
+		t.Errorf("online users = %v, want [2]", got)
+	}
+
+	// The per-user key itself expires too, so nothing is left behind
+	mr.FastForward(3 * time.Second)
+	if mr.Exists(redisUserKeyPrefix + "1") {
+		t.Error("presence key of the expired user was not removed")
+	}
+}
+
+func TestFallsBackToLocalBroker(t *testing.T) {
+	useLocalBroker(t)
+
+	// Redis requested but not configured
+	t.Setenv("WS_BROKER", "redis")
+	InitBroker(nil)
+	if b, _ := currentBroker(); b.Name() != "local" {
+		t.Fatalf("broker = %s without Redis, want local", b.Name())
+	}
+
+	// Redis configured but unreachable: the subscription fails and the local broker stays
+	mr, rdb := newRedis(t)
+	mr.Close()
+	UseBroker(NewRedisBroker(rdb, InstanceID), NewRedisPresence(rdb, InstanceID, time.Minute))
+	if b, _ := currentBroker(); b.Name() != "local" {
+		t.Fatalf("broker = %s after a failed subscription, want local", b.Name())
+	}
+
+	// Local delivery keeps working
+	local := connect(t, 3, "conn-3")
+	SendMessageToUser(websocket.TextMessage, "hello", 3)
+	if got := receive(t, local); got != "hello" {
+		t.Errorf("local connection got %q", got)
+	}
+
+	// Presence answers from this instance's connections when Redis is down
+	down := NewRedisPresence(rdb, InstanceID, time.Minute)
+	if !down.IsOnline(3) || down.IsOnline(4) {
+		t.Error("presence did not fall back to local connections")
+	}
+	if got := down.OnlineUsers(); !slices.Equal(got, []uint{3}) {
+		t.Errorf("online users = %v, want [3]", got)
+	}
+}
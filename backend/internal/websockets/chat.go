@@ -0,0 +1,77 @@
+package websockets
+
+import (
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/moderation"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ReceiptPayload tells the chat which messages were delivered to or read by a participant
+type ReceiptPayload struct {
+	ChatID     uint      `json:"chat_id"`
+	MessageIDs []uint    `json:"message_ids"`
+	By         uint      `json:"by"` // The participant who received/read them
+	At         time.Time `json:"at"`
+}
+
+// MessageDeletedPayload identifies a message that was deleted
+type MessageDeletedPayload struct {
+	ID     uint `json:"id"`
+	ChatID uint `json:"chat_id"`
+}
+
+// PushChatMessage sends a message.new / message.edited event to everyone in the chat,
+// so the recipients see it and the sender's other tabs stay in sync
+func PushChatMessage(db *gorm.DB, eventType string, message model.Message) {
+	pushToMembers(db, message.ChatID, eventType, message)
+}
+
+// PushMessageDeleted notifies everyone in the chat that a message was deleted
+func PushMessageDeleted(db *gorm.DB, message model.Message) {
+	pushToMembers(db, message.ChatID, EventMessageDeleted, MessageDeletedPayload{
+		ID:     message.ID,
+		ChatID: message.ChatID,
+	})
+}
+
+// PushReceipt sends a message.delivered / message.read receipt to everyone in the chat,
+// including the reader's other tabs
+func PushReceipt(db *gorm.DB, eventType string, receipt ReceiptPayload) {
+	if len(receipt.MessageIDs) == 0 {
+		return
+	}
+	pushToMembers(db, receipt.ChatID, eventType, receipt)
+}
+
+func pushToMembers(db *gorm.DB, chatID uint, eventType string, payload any) {
+	for _, userID := range chatroom.Members(db, chatID) {
+		if err := SendEvent(userID, eventType, payload); err != nil {
+			logrus.Errorf("Failed to push %s to user %d: %v", eventType, userID, err)
+			return
+		}
+	}
+}
+
+// sharesChat reports whether two users are in a conversation together and neither has blocked the other;
+// a non-zero chatID must be that conversation
+func sharesChat(db *gorm.DB, userA, userB, chatID uint) bool {
+	if moderation.IsBlocked(db, userA, userB) {
+		return false
+	}
+
+	query := db.Model(&model.ChatParticipant{}).
+		Where("user_id = ? AND chat_id IN (?)", userB, chatroom.Joined(db, userA))
+	if chatID != 0 {
+		query = query.Where("chat_id = ?", chatID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
@@ -0,0 +1,146 @@
+package websockets
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const writeWait = 10 * time.Second
+
+var (
+	messagesSent    atomic.Int64
+	messagesDropped atomic.Int64
+	slowEvicted     atomic.Int64
+)
+
+type outbound struct {
+	messageType int
+	data        []byte
+}
+
+// Client is one websocket connection (one browser tab).
+// Only the client's write pump writes to Conn; everyone else goes through Send.
+type Client struct {
+	UserID       uint
+	ConnectionID string
+	Email        string
+	Conn         *websocket.Conn
+	LastActive   time.Time
+
+	send      chan outbound
+	done      chan struct{}
+	closeOnce sync.Once
+	closeCode int
+	closeText string
+	dropped   atomic.Int64
+
+	// Read from WS_* when the client connects, after the env file is loaded
+	maxMessageBytes int64
+	pongWait        time.Duration
+}
+
+func newClient(userID uint, connectionID, email string, conn *websocket.Conn) *Client {
+	return &Client{
+		UserID:       userID,
+		ConnectionID: connectionID,
+		Email:        email,
+		Conn:         conn,
+		LastActive:   time.Now(),
+		send:         make(chan outbound, util.Getenv("WS_SEND_BUFFER", 256)),
+		done:         make(chan struct{}),
+
+		maxMessageBytes: int64(util.Getenv("WS_MAX_MESSAGE_BYTES", 64*1024)),
+		pongWait:        time.Duration(util.Getenv("WS_PONG_WAIT_S", 60)) * time.Second,
+	}
+}
+
+// Send queues a frame without blocking. A client whose buffer is full is too slow to keep up
+// and is disconnected so it can reconnect and resync instead of silently missing messages.
+func (c *Client) Send(messageType int, data []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- outbound{messageType: messageType, data: data}:
+		return true
+	default:
+		c.dropped.Add(1)
+		messagesDropped.Add(1)
+		if c.Close(websocket.ClosePolicyViolation, "send buffer full") {
+			slowEvicted.Add(1)
+			logrus.Warnf("Evicted slow websocket consumer %s (user %d)", c.ConnectionID, c.UserID)
+		}
+		return false
+	}
+}
+
+// Close asks the write pump to send a close frame and shut the connection down.
+// It reports whether this call initiated the close.
+func (c *Client) Close(code int, text string) bool {
+	closed := false
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeText = text
+		close(c.done)
+		closed = true
+	})
+	return closed
+}
+
+// Dropped returns the number of frames dropped for this connection
+func (c *Client) Dropped() int64 {
+	return c.dropped.Load()
+}
+
+// writePump is the only goroutine writing to the connection: queued frames, heartbeats and the close frame
+func (c *Client) writePump() {
+	// Ping well within the peer's pong deadline
+	ticker := time.NewTicker(c.pongWait * 9 / 10)
+	defer func() {
+		ticker.Stop()
+		c.Conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.Conn.WriteMessage(msg.messageType, msg.data); err != nil {
+				logrus.Printf("Error sending to connection %s: %v", c.ConnectionID, err)
+				c.Close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+			messagesSent.Add(1)
+
+		case <-ticker.C:
+			if err := c.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.Close(websocket.CloseAbnormalClosure, "")
+				return
+			}
+
+		case <-c.done:
+			if c.closeCode != websocket.CloseAbnormalClosure {
+				msg := websocket.FormatCloseMessage(c.closeCode, c.closeText)
+				c.Conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+			}
+			return
+		}
+	}
+}
+
+// prepareRead applies the size limit and heartbeat deadlines to the read side
+func (c *Client) prepareRead() {
+	c.Conn.SetReadLimit(c.maxMessageBytes)
+	c.Conn.SetReadDeadline(time.Now().Add(c.pongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(c.pongWait))
+	})
+}
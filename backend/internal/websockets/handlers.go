@@ -0,0 +1,54 @@
+package websockets
+
+import (
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/moderation"
+)
+
+func init() {
+	On(EventTypingStart, handleTyping(EventTypingStart))
+	On(EventTypingStop, handleTyping(EventTypingStop))
+}
+
+// TypingPayload is sent by the client ({"chat_id": chatID} or {"to": userID}) and relayed to the
+// recipients ({"from": userID})
+type TypingPayload struct {
+	To     uint `json:"to,omitempty"`
+	From   uint `json:"from,omitempty"`
+	ChatID uint `json:"chat_id,omitempty"`
+}
+
+// handleTyping relays typing indicators to every tab of the recipients. With a chat_id it goes to everyone
+// else in that chat, otherwise to the single user in to. Users may only signal people they share a chat with.
+func handleTyping(eventType string) EventHandler {
+	return func(ctx *Context) (any, error) {
+		var in TypingPayload
+		if err := ctx.Bind(&in); err != nil {
+			return nil, err
+		}
+		out := TypingPayload{From: ctx.Client.UserID, ChatID: in.ChatID}
+
+		if in.To == 0 && in.ChatID != 0 {
+			if !chatroom.CanAccess(ctx.DB, in.ChatID, ctx.Client.UserID) {
+				return nil, NewError(ErrCodeForbidden, "you are not in this chat")
+			}
+			for _, userID := range chatroom.Members(ctx.DB, in.ChatID) {
+				if userID == ctx.Client.UserID || moderation.IsBlocked(ctx.DB, ctx.Client.UserID, userID) {
+					continue
+				}
+				if err := SendEvent(userID, eventType, out); err != nil {
+					return nil, err
+				}
+			}
+			return nil, nil
+		}
+
+		if in.To == 0 || in.To == ctx.Client.UserID {
+			return nil, NewError(ErrCodeBadRequest, "to must be another user's ID")
+		}
+		if !sharesChat(ctx.DB, ctx.Client.UserID, in.To, in.ChatID) {
+			return nil, NewError(ErrCodeForbidden, "you don't have a chat with this user")
+		}
+		return nil, SendEvent(in.To, eventType, out)
+	}
+}
@@ -0,0 +1,74 @@
+package websockets
+
+import (
+	"slices"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/internal/chatroom"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
+	"github.com/faiz-muttaqin/lgs/backend/internal/moderation"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// PresencePayload is pushed to chat partners when a user comes online or goes offline
+type PresencePayload struct {
+	UserID     uint       `json:"user_id"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// ApplyPresence fills the user's virtual online/last_seen_at fields, hiding both when the user opted out
+func ApplyPresence(u *model.User) {
+	online := false
+	if !u.HideOnlineStatus {
+		online = IsUserConnected(u.ID)
+		u.LastSeen = u.LastSeenAt
+	} else {
+		u.LastSeen = nil
+	}
+	u.Online = &online
+}
+
+// ChatPartners returns the IDs of everyone the user shares a chat with, leaving out anyone on either
+// side of a block
+func ChatPartners(db *gorm.DB, userID uint) []uint {
+	var ids []uint
+	if err := db.Model(&model.ChatParticipant{}).
+		Distinct("user_id").
+		Where("chat_id IN (?) AND user_id <> ?", chatroom.Joined(db, userID), userID).
+		Pluck("user_id", &ids).Error; err != nil {
+		return nil
+	}
+
+	blocked := moderation.BlockedUserIDs(db, userID)
+	partners := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !slices.Contains(blocked, id) {
+			partners = append(partners, id)
+		}
+	}
+	return partners
+}
+
+// AnnouncePresence pushes the user's current presence to their chat partners.
+// Users who hide their status are always announced as offline without a last seen time.
+func AnnouncePresence(db *gorm.DB, userID uint) {
+	if db == nil {
+		return
+	}
+
+	var user model.User
+	if err := db.Select("id", "last_seen_at", "hide_online_status").First(&user, userID).Error; err != nil {
+		return
+	}
+	ApplyPresence(&user)
+
+	payload := PresencePayload{UserID: userID, Online: *user.Online, LastSeenAt: user.LastSeen}
+	for _, partnerID := range ChatPartners(db, userID) {
+		if err := SendEvent(partnerID, EventPresence, payload); err != nil {
+			logrus.Errorf("Failed to push presence of user %d: %v", userID, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package websockets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ProtocolVersion is the envelope version spoken by the server
+const ProtocolVersion = 1
+
+// Event types. Client-originated events are acked when they carry an id.
+const (
+	EventAck          = "ack"
+	EventError        = "error"
+	EventTypingStart  = "typing.start"
+	EventTypingStop   = "typing.stop"
+	EventNotification = "notification"
+	EventPresence     = "presence.changed"
+
+	EventMessageNew       = "message.new"
+	EventMessageEdited    = "message.edited"
+	EventMessageDeleted   = "message.deleted"
+	EventMessageDelivered = "message.delivered"
+	EventMessageRead      = "message.read"
+)
+
+// Error codes sent in error events
+const (
+	ErrCodeBadRequest         = "bad_request"
+	ErrCodeUnsupportedVersion = "unsupported_version"
+	ErrCodeUnknownType        = "unknown_type"
+	ErrCodeForbidden          = "forbidden"
+	ErrCodeInternal           = "internal_error"
+)
+
+// Envelope is the frame exchanged in both directions:
+//
+//	{"v":1,"type":"typing.start","id":"c-42","payload":{"to":7},"ts":1700000000000}
+type Envelope struct {
+	V       int             `json:"v"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"` // Set by the client to receive an ack; echoed as "ref"
+	Payload json.RawMessage `json:"payload,omitempty"`
+	TS      int64           `json:"ts"` // Unix milliseconds
+}
+
+// AckPayload confirms a client event was handled
+type AckPayload struct {
+	Ref  string `json:"ref"`
+	Data any    `json:"data,omitempty"`
+}
+
+// Error is returned by event handlers and sent to the client as an error event
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Ref     string `json:"ref,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// NewError builds a handler error with a client-visible code
+func NewError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Context is passed to event handlers
+type Context struct {
+	Client *Client
+	DB     *gorm.DB
+	Event  Envelope
+}
+
+// Bind decodes the event payload into v
+func (ctx *Context) Bind(v any) error {
+	if len(ctx.Event.Payload) == 0 {
+		return NewError(ErrCodeBadRequest, "payload is required")
+	}
+	if err := json.Unmarshal(ctx.Event.Payload, v); err != nil {
+		return NewError(ErrCodeBadRequest, "invalid payload: "+err.Error())
+	}
+	return nil
+}
+
+// EventHandler handles one client event type. The returned value is included in the ack.
+type EventHandler func(ctx *Context) (any, error)
+
+var (
+	handlers     = map[string]EventHandler{}
+	handlerMutex sync.RWMutex
+)
+
+// On registers the handler for a client event type
+func On(eventType string, handler EventHandler) {
+	handlerMutex.Lock()
+	defer handlerMutex.Unlock()
+
+	if _, exists := handlers[eventType]; exists {
+		panic(fmt.Sprintf("websockets: handler already registered for %q", eventType))
+	}
+	handlers[eventType] = handler
+}
+
+// Encode wraps a payload in a server envelope
+func Encode(eventType string, payload any) ([]byte, error) {
+	env := Envelope{
+		V:    ProtocolVersion,
+		Type: eventType,
+		TS:   time.Now().UnixMilli(),
+	}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s payload: %w", eventType, err)
+		}
+		env.Payload = raw
+	}
+	return json.Marshal(env)
+}
+
+// SendEvent sends a typed event to all of the user's connections
+func SendEvent(userID uint, eventType string, payload any) error {
+	data, err := Encode(eventType, payload)
+	if err != nil {
+		return err
+	}
+	SendMessageToUser(websocket.TextMessage, string(data), userID)
+	return nil
+}
+
+// SendEventToConnection sends a typed event to a single connection
+func SendEventToConnection(connectionID string, eventType string, payload any) error {
+	data, err := Encode(eventType, payload)
+	if err != nil {
+		return err
+	}
+	SendMessageToConnection(websocket.TextMessage, string(data), connectionID)
+	return nil
+}
+
+// dispatch decodes a client frame, runs its handler and replies with an ack or error on the same connection
+func dispatch(client *Client, db *gorm.DB, frame []byte) {
+	var env Envelope
+	if err := json.Unmarshal(frame, &env); err != nil || env.Type == "" {
+		sendError(client, NewError(ErrCodeBadRequest, "frame must be a JSON envelope with a type"))
+		return
+	}
+	if env.V != ProtocolVersion {
+		sendError(client, &Error{Code: ErrCodeUnsupportedVersion, Message: fmt.Sprintf("protocol version %d is not supported", env.V), Ref: env.ID})
+		return
+	}
+
+	handlerMutex.RLock()
+	handler, ok := handlers[env.Type]
+	handlerMutex.RUnlock()
+	if !ok {
+		sendError(client, &Error{Code: ErrCodeUnknownType, Message: "unknown event type " + env.Type, Ref: env.ID})
+		return
+	}
+
+	result, err := safeHandle(handler, &Context{Client: client, DB: db, Event: env})
+	if err != nil {
+		var wsErr *Error
+		if !errors.As(err, &wsErr) {
+			logrus.Errorf("Websocket handler %s failed for user %d: %v", env.Type, client.UserID, err)
+			wsErr = NewError(ErrCodeInternal, "failed to handle event")
+		}
+		sendError(client, &Error{Code: wsErr.Code, Message: wsErr.Message, Ref: env.ID})
+		return
+	}
+
+	if env.ID != "" {
+		SendEventToConnection(client.ConnectionID, EventAck, AckPayload{Ref: env.ID, Data: result})
+	}
+}
+
+// safeHandle runs a handler, turning a panic into an error so one bad handler can't drop the connection
+func safeHandle(handler EventHandler, ctx *Context) (result any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handler(ctx)
+}
+
+func sendError(client *Client, e *Error) {
+	SendEventToConnection(client.ConnectionID, EventError, e)
+}
@@ -0,0 +1,79 @@
+package websockets
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/faiz-muttaqin/lgs/backend/pkg/kvstore"
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+)
+
+const ticketKeyPrefix = "ws:ticket:"
+
+// IssueTicket creates a single-use connection ticket for the user.
+// Browsers can't set an Authorization header on the upgrade request, so they pass it as ?ticket= instead.
+func IssueTicket(userID uint) (string, time.Duration, error) {
+	ticket, err := util.GenerateSecureToken(32)
+	if err != nil {
+		return "", 0, err
+	}
+
+	ttl := time.Duration(util.Getenv("WS_TICKET_TTL_S", 30)) * time.Second
+	if err := kvstore.SetKey(ticketKeyPrefix+ticket, strconv.FormatUint(uint64(userID), 10), ttl); err != nil {
+		return "", 0, err
+	}
+	return ticket, ttl, nil
+}
+
+// ConsumeTicket redeems a ticket and returns the user it was issued to; a ticket works only once
+func ConsumeTicket(ticket string) (uint, error) {
+	if ticket == "" {
+		return 0, fmt.Errorf("ticket missing")
+	}
+
+	value, err := kvstore.PopKey(ticketKeyPrefix + ticket)
+	if err != nil {
+		return 0, fmt.Errorf("ticket invalid or expired")
+	}
+	userID, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("ticket invalid")
+	}
+	return uint(userID), nil
+}
+
+// checkOrigin accepts the upgrade when the Origin is in WS_ALLOWED_ORIGINS (comma separated, "*" allows any).
+// Without a list only same-host origins are accepted, except in dev mode. Requests without an Origin
+// header don't come from a browser and are allowed.
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	allowed := allowedOrigins()
+	if len(allowed) == 0 {
+		if util.IsDevMode() {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, r.Host)
+	}
+	return slices.Contains(allowed, "*") || slices.Contains(allowed, strings.TrimSuffix(strings.ToLower(origin), "/"))
+}
+
+func allowedOrigins() []string {
+	var origins []string
+	for _, o := range strings.Split(os.Getenv("WS_ALLOWED_ORIGINS"), ",") {
+		if o = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(o)), "/"); o != "" {
+			origins = append(origins, o)
+		}
+	}
+	return origins
+}
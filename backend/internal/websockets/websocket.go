@@ -4,7 +4,6 @@ import (
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -17,9 +16,7 @@ import (
 
 var (
 	Upgrader = websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool {
-			return true
-		},
+		CheckOrigin: checkOrigin,
 	}
 
 	// Clients maps connectionID to Client info
@@ -29,14 +26,6 @@ var (
 	Mutex           = sync.RWMutex{}
 )
 
-type Client struct {
-	UserID       uint
-	ConnectionID string
-	Email        string
-	Conn         *websocket.Conn
-	LastActive   time.Time
-}
-
 func HandleWebSocket(w http.ResponseWriter, r *http.Request, user *model.User, db *gorm.DB) {
 	conn, err := Upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -47,22 +36,24 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request, user *model.User, d
 	// Generate unique connection ID
 	connectionID := uuid.New().String()
 
-	client := &Client{
-		UserID:       user.ID,
-		ConnectionID: connectionID,
-		Email:        user.Email.String(),
-		Conn:         conn,
-		LastActive:   time.Now(),
-	}
+	client := newClient(user.ID, connectionID, user.Email.String(), conn)
 
 	// Add client to maps
 	Mutex.Lock()
 	Clients[connectionID] = client
 	UserConnections[user.ID] = append(UserConnections[user.ID], connectionID)
+	firstConnection := len(UserConnections[user.ID]) == 1
 	logrus.Printf("User %s (ID: %d) connected. Connection ID: %s. Total connections for user: %d",
 		user.Email, user.ID, connectionID, len(UserConnections[user.ID]))
 	Mutex.Unlock()
 
+	if firstConnection {
+		_, p := currentBroker()
+		p.SetOnline(user.ID, true)
+		go AnnouncePresence(db, user.ID)
+	}
+
+	go client.writePump()
 	go HandleMessages(connectionID, client, db)
 }
 
@@ -73,6 +64,7 @@ func HandleMessages(connectionID string, client *Client, db *gorm.DB) {
 		delete(Clients, connectionID)
 
 		// Remove from user's connection list
+		lastConnection := false
 		if connections, exists := UserConnections[client.UserID]; exists {
 			newConnections := []string{}
 			for _, connID := range connections {
@@ -84,6 +76,7 @@ func HandleMessages(connectionID string, client *Client, db *gorm.DB) {
 				UserConnections[client.UserID] = newConnections
 			} else {
 				delete(UserConnections, client.UserID)
+				lastConnection = true
 			}
 		}
 
@@ -91,16 +84,26 @@ func HandleMessages(connectionID string, client *Client, db *gorm.DB) {
 			client.Email, client.UserID, connectionID, len(UserConnections[client.UserID]))
 		Mutex.Unlock()
 
-		client.Conn.Close()
+		// Stops the write pump, which closes the connection
+		client.Close(websocket.CloseNormalClosure, "")
+
+		if lastConnection {
+			_, p := currentBroker()
+			p.SetOnline(client.UserID, false)
+		}
 
 		// Check if user has any remaining connections
-		go checkForReconnection(client.UserID, client.Email, db)
+		go checkForReconnection(client.UserID, client.Email, time.Now(), db)
 	}()
+	client.prepareRead()
+
 	// Listening for incoming messages
 	for {
 		messageType, p, err := client.Conn.ReadMessage()
 		if err != nil {
-			logrus.Println(err)
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				logrus.Println(err)
+			}
 			return
 		}
 
@@ -109,73 +112,34 @@ func HandleMessages(connectionID string, client *Client, db *gorm.DB) {
 		client.LastActive = time.Now()
 		Mutex.Unlock()
 
-		logrus.Printf("Received message from user %s (connection %s): %s", client.Email, connectionID, string(p))
-
-		if messageType == websocket.TextMessage {
-			if strings.HasPrefix(string(p), "TYPING_ON:") {
-				userIDStr := strings.TrimPrefix(string(p), "TYPING_ON:")
-				recipientID, err := strconv.ParseUint(userIDStr, 10, 32)
-				if err != nil {
-					logrus.Println("Invalid TYPING message format:", string(p))
-					continue
-				}
-				SendMessageToUser(websocket.TextMessage, string(p), uint(recipientID))
-			}
-			if strings.HasPrefix(string(p), "TYPING_OFF:") {
-				userIDStr := strings.TrimPrefix(string(p), "TYPING_OFF:")
-				recipientID, err := strconv.ParseUint(userIDStr, 10, 32)
-				if err != nil {
-					logrus.Println("Invalid TYPING message format:", string(p))
-					continue
-				}
-				SendMessageToUser(websocket.TextMessage, string(p), uint(recipientID))
-			}
+		if messageType != websocket.TextMessage {
+			SendEventToConnection(connectionID, EventError, NewError(ErrCodeBadRequest, "only text frames are supported"))
+			continue
 		}
+		dispatch(client, db, p)
 	}
 }
-func HandleMessage(messageType int, message []byte, sender *Client) {
-	// Example: Assume messages have the format "recipientUserID:message"
-	parts := strings.SplitN(string(message), ":", 2)
-	if len(parts) != 2 {
-		logrus.Println("Invalid message format:", string(message))
-		return
-	}
 
-	recipientUserID, err := strconv.ParseUint(parts[0], 10, 32)
-	if err != nil {
-		logrus.Println("Invalid recipient user ID:", parts[0])
-		return
-	}
-
-	actualMessage := parts[1]
-
-	// Send to specific user (all their tabs)
-	SendMessageToUser(messageType, actualMessage, uint(recipientUserID))
+// SendMessageToUser sends message to ALL tabs/connections of a specific user, on every instance
+func SendMessageToUser(messageType int, message string, userID uint) {
+	sendToUserLocal(messageType, []byte(message), userID)
+	publish(userID, messageType, []byte(message))
 }
 
-// SendMessageToUser sends message to ALL tabs/connections of a specific user
-func SendMessageToUser(messageType int, message string, userID uint) {
+func sendToUserLocal(messageType int, data []byte, userID uint) {
 	Mutex.RLock()
-	connectionIDs := UserConnections[userID]
-	Mutex.RUnlock()
+	defer Mutex.RUnlock()
 
+	connectionIDs := UserConnections[userID]
 	if len(connectionIDs) == 0 {
-		logrus.Printf("User ID %d is not connected", userID)
+		logrus.Debugf("User ID %d is not connected to this instance", userID)
 		return
 	}
 
 	successCount := 0
-	Mutex.RLock()
-	defer Mutex.RUnlock()
-
 	for _, connID := range connectionIDs {
-		if client, ok := Clients[connID]; ok && client.Conn != nil {
-			err := client.Conn.WriteMessage(messageType, []byte(message))
-			if err != nil {
-				logrus.Printf("Error sending to connection %s: %v", connID, err)
-			} else {
-				successCount++
-			}
+		if client, ok := Clients[connID]; ok && client.Send(messageType, data) {
+			successCount++
 		}
 	}
 
@@ -187,39 +151,27 @@ func SendMessageToConnection(messageType int, message string, connectionID strin
 	Mutex.RLock()
 	defer Mutex.RUnlock()
 
-	if client, ok := Clients[connectionID]; ok && client.Conn != nil {
-		err := client.Conn.WriteMessage(messageType, []byte(message))
-		if err != nil {
-			logrus.Println(err)
-		}
+	if client, ok := Clients[connectionID]; ok {
+		client.Send(messageType, []byte(message))
 	}
 }
 
-// CloseUserConnections closes all connections for a user
+// CloseUserConnections closes all connections for a user with a close frame.
+// The connections' read loops remove them from the maps once the socket is closed.
 func CloseUserConnections(userID uint) {
-	Mutex.Lock()
-	connectionIDs := UserConnections[userID]
-	Mutex.Unlock()
-
-	for _, connID := range connectionIDs {
-		Mutex.RLock()
-		if client, ok := Clients[connID]; ok && client.Conn != nil {
-			client.Conn.Close()
+	Mutex.RLock()
+	for _, connID := range UserConnections[userID] {
+		if client, ok := Clients[connID]; ok {
+			client.Close(websocket.CloseNormalClosure, "session closed")
 		}
-		Mutex.RUnlock()
 	}
-
-	Mutex.Lock()
-	for _, connID := range connectionIDs {
-		delete(Clients, connID)
-	}
-	delete(UserConnections, userID)
-	Mutex.Unlock()
+	Mutex.RUnlock()
 
 	logrus.Printf("Closed all connections for user ID %d", userID)
 }
 
-func checkForReconnection(userID uint, email string, db *gorm.DB) {
+// checkForReconnection marks the user offline if they don't reconnect within MAX_DISCONECTION_TIME_S
+func checkForReconnection(userID uint, email string, disconnectedAt time.Time, db *gorm.DB) {
 	disconectionTimeStr := os.Getenv("MAX_DISCONECTION_TIME_S")
 	disconectionExpiredSeconds, err := strconv.Atoi(disconectionTimeStr)
 	if err != nil {
@@ -229,34 +181,31 @@ func checkForReconnection(userID uint, email string, db *gorm.DB) {
 	// Wait for timeout
 	time.Sleep(time.Duration(disconectionExpiredSeconds) * time.Second)
 
-	// Check if user still has ANY active connections
-	Mutex.RLock()
-	hasConnections := len(UserConnections[userID]) > 0
-	Mutex.RUnlock()
+	// Check if user still has ANY active connections, on any instance
+	hasConnections := IsUserConnected(userID)
 
 	if !hasConnections {
 		logrus.Printf("User %s (ID: %d) has no active connections after %d seconds", email, userID, disconectionExpiredSeconds)
 		updates := map[string]interface{}{
-			"Session":        "",
-			"SessionExpired": 0,
+			"session":      "",
+			"last_seen_at": disconnectedAt,
 		}
 
 		if err := db.Model(&model.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
 			logrus.Errorf("Failed to update user session: %v", err)
 			return
 		}
+		AnnouncePresence(db, userID)
 	}
 }
 
-// IsUserConnected checks if a user has any active connections
+// IsUserConnected checks if a user has any active connections across the cluster
 func IsUserConnected(userID uint) bool {
-	Mutex.RLock()
-	defer Mutex.RUnlock()
-
-	return len(UserConnections[userID]) > 0
+	_, p := currentBroker()
+	return p.IsOnline(userID)
 }
 
-// GetUserConnectionCount returns number of active connections for a user
+// GetUserConnectionCount returns number of active connections for a user on this instance
 func GetUserConnectionCount(userID uint) int {
 	Mutex.RLock()
 	defer Mutex.RUnlock()
@@ -264,20 +213,20 @@ func GetUserConnectionCount(userID uint) int {
 	return len(UserConnections[userID])
 }
 
-// BroadcastMessage sends a message to all connected clients (all users, all tabs)
+// BroadcastMessage sends a message to all connected clients (all users, all tabs, all instances)
 func BroadcastMessage(messageType int, message string) {
+	broadcastLocal(messageType, []byte(message))
+	publish(0, messageType, []byte(message))
+}
+
+func broadcastLocal(messageType int, data []byte) {
 	Mutex.RLock()
 	defer Mutex.RUnlock()
 
 	count := 0
 	for _, client := range Clients {
-		if client.Conn != nil {
-			err := client.Conn.WriteMessage(messageType, []byte(message))
-			if err != nil {
-				logrus.Errorf("Error broadcasting to connection %s: %v", client.ConnectionID, err)
-			} else {
-				count++
-			}
+		if client.Send(messageType, data) {
+			count++
 		}
 	}
 
@@ -291,27 +240,43 @@ func BroadcastToUsers(messageType int, message string, userIDs []uint) {
 	}
 }
 
-// GetOnlineUsers returns list of currently connected user IDs
+// GetOnlineUsers returns list of currently connected user IDs across the cluster
 func GetOnlineUsers() []uint {
-	Mutex.RLock()
-	defer Mutex.RUnlock()
+	_, p := currentBroker()
+	return p.OnlineUsers()
+}
 
+func onlineUsers() []uint {
 	userIDs := make([]uint, 0, len(UserConnections))
 	for userID := range UserConnections {
 		userIDs = append(userIDs, userID)
 	}
-
 	return userIDs
 }
 
-// GetConnectionStats returns connection statistics
+// GetConnectionStats returns connection statistics; connection counts are for this instance
 func GetConnectionStats() map[string]interface{} {
+	b, _ := currentBroker()
+	clusterOnline := GetOnlineUsers()
+
 	Mutex.RLock()
 	defer Mutex.RUnlock()
 
+	queued := 0
+	for _, client := range Clients {
+		queued += len(client.send)
+	}
+
 	return map[string]interface{}{
-		"total_connections": len(Clients),
-		"total_users":       len(UserConnections),
-		"online_users":      GetOnlineUsers(),
+		"total_connections":      len(Clients),
+		"total_users":            len(UserConnections),
+		"online_users":           onlineUsers(),
+		"cluster_online_users":   clusterOnline,
+		"broker":                 b.Name(),
+		"instance_id":            InstanceID,
+		"queued_messages":        queued,
+		"messages_sent":          messagesSent.Load(),
+		"messages_dropped":       messagesDropped.Load(),
+		"slow_consumers_evicted": slowEvicted.Load(),
 	}
 }
@@ -5,17 +5,65 @@ import (
 
 	"github.com/faiz-muttaqin/lgs/backend/internal/database"
 	"github.com/faiz-muttaqin/lgs/backend/internal/helper"
+	"github.com/faiz-muttaqin/lgs/backend/internal/model"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
+// WebsocketHandlerGin upgrades the connection. Browsers authenticate with ?ticket= from WebsocketTicketHandler;
+// other clients may still send the Authorization header.
 func WebsocketHandlerGin(c *gin.Context) {
+	var userData *model.User
+	if ticket := c.Query("ticket"); ticket != "" {
+		userID, err := ConsumeTicket(ticket)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: " + err.Error()})
+			return
+		}
+		var user model.User
+		if err := database.DB.First(&user, userID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		userData = &user
+	} else {
+		user, err := helper.GetFirebaseUser(c)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		userData = user
+	}
+
+	logrus.Printf("WebSocket connection attempt from user: %s (ID: %d)", userData.Email, userData.ID)
+	HandleWebSocket(c.Writer, c.Request, userData, database.DB)
+}
+
+// WebsocketTicketHandler issues a short-lived, single-use ticket for opening /ws
+func WebsocketTicketHandler(c *gin.Context) {
 	userData, err := helper.GetFirebaseUser(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Unauthorized"})
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"message": "Unauthorized",
+		})
 		return
 	}
 
-	logrus.Printf("WebSocket connection attempt from user: %s (ID: %d)", userData.Email, userData.ID)
-	HandleWebSocket(c.Writer, c.Request, userData, database.DB)
+	ticket, ttl, err := IssueTicket(userData.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "Failed to issue websocket ticket",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"ticket":     ticket,
+			"expires_in": int(ttl.Seconds()),
+		},
+	})
 }
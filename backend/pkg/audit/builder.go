@@ -36,6 +36,14 @@ func Delete(resource string, id any) *Entry {
 	}
 }
 
+func Export(resource string, id any) *Entry {
+	return &Entry{
+		Action:     "EXPORT",
+		Resource:   resource,
+		ResourceID: fmt.Sprint(id),
+	}
+}
+
 func (e *Entry) Before(v any) *Entry {
 	if v != nil {
 		b, _ := json.Marshal(v)
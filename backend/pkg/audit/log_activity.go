@@ -15,12 +15,12 @@ type LogActivity struct {
 	UserAgent string `json:"user_agent" gorm:"column:user_agent;type:text"`
 
 	// ===== Action =====
-	Action     string `json:"action" gorm:"column:action;size:32;index"`           // CREATE | UPDATE | DELETE | LOGIN | APPROVE
+	Action     string `json:"action" gorm:"column:action;size:32;index"`           // CREATE | UPDATE | DELETE | EXPORT | LOGIN | APPROVE
 	Resource   string `json:"resource" gorm:"column:resource;size:64;index"`       // merchant, terminal, user
 	ResourceID string `json:"resource_id" gorm:"column:resource_id;size:64;index"` // "123", UUID, serial number
 
 	// ===== Request Context =====
-	ReqMethod string `json:"req_method" gorm:"column:req_method;size:8"`
+	ReqMethod string `json:"req_method" gorm:"column:req_method;size:8"` // JOB for background jobs
 	ReqURI    string `json:"req_uri" gorm:"column:req_uri;type:text"`
 
 	// ===== Change Tracking =====
@@ -37,3 +37,31 @@ func Log(
 	// Non-blocking safety: do not break request if logging fails
 	_ = db.Create(&log).Error
 }
+
+// LogSystem records an entry made by a background job instead of a request; job is stored as the URI
+func LogSystem(
+	db *gorm.DB,
+	job string,
+	entry *Entry,
+) {
+	if db == nil || entry == nil {
+		return
+	}
+
+	log := LogActivity{
+		Action:     entry.Action,
+		Resource:   entry.Resource,
+		ResourceID: entry.ResourceID,
+
+		ReqMethod: "JOB",
+		ReqURI:    job,
+
+		BeforeData: entry.BeforeData,
+		AfterData:  entry.AfterData,
+
+		Status:  entry.Status,
+		Message: entry.Message,
+	}
+
+	_ = db.Create(&log).Error
+}
@@ -16,6 +16,7 @@ var (
 	RDB       *redis.Client
 	redisUp   atomic.Bool
 	shardMaps [256]*sync.Map
+	counterMu sync.Mutex // Serializes IncrKey on the in-memory fallback
 )
 
 type valueWithTTL struct {
@@ -126,6 +127,74 @@ func GetKey(key string) (string, error) {
 	return "", fmt.Errorf("key not found")
 }
 
+// PopKey retrieves a value and deletes the key atomically, so only one caller can ever get it
+func PopKey(key string) (string, error) {
+	if redisUp.Load() {
+		val, err := RDB.GetDel(context.Background(), key).Result()
+		if err == nil {
+			return val, nil
+		}
+		if err == redis.Nil {
+			return "", fmt.Errorf("key not found")
+		}
+		redisUp.Store(false)
+	}
+
+	shard := getShard(key)
+	if val, ok := shard.LoadAndDelete(key); ok {
+		v := val.(valueWithTTL)
+		if time.Now().Before(v.ttl) {
+			return v.value, nil
+		}
+	}
+	return "", fmt.Errorf("key not found")
+}
+
+// incrScript increments and sets the expiry in one step, so a counter is never left without a TTL. The
+// expiry is also set on a counter that lost its TTL, instead of only on the first increment.
+var incrScript = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if redis.call('PTTL', KEYS[1]) < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n`)
+
+// IncrKey increments a counter and returns the new value. The TTL is set when the counter is created,
+// which makes it a fixed window suitable for rate limiting.
+func IncrKey(key string, ttl time.Duration) (int64, error) {
+	if redisUp.Load() {
+		n, err := incrScript.Run(context.Background(), RDB, []string{key}, ttl.Milliseconds()).Int64()
+		if err == nil {
+			return n, nil
+		}
+		redisUp.Store(false)
+	}
+
+	counterMu.Lock()
+	defer counterMu.Unlock()
+
+	shard := getShard(key)
+	v := valueWithTTL{value: "0", ttl: time.Now().Add(ttl)}
+	if val, ok := shard.Load(key); ok && time.Now().Before(val.(valueWithTTL).ttl) {
+		v = val.(valueWithTTL)
+	}
+	n, _ := strconv.ParseInt(v.value, 10, 64)
+	n++
+	v.value = strconv.FormatInt(n, 10)
+	shard.Store(key, v)
+	if n == 1 {
+		expires := v.ttl
+		time.AfterFunc(ttl, func() {
+			counterMu.Lock()
+			defer counterMu.Unlock()
+			if cur, ok := shard.Load(key); ok && cur.(valueWithTTL).ttl.Equal(expires) {
+				shard.Delete(key)
+			}
+		})
+	}
+	return n, nil
+}
+
 // ExistsIn checks if a key exists
 func ExistsIn(key string) (bool, error) {
 	if redisUp.Load() {
@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FakeDimensions is the length of the fake embedding vectors
+const FakeDimensions = 256
+
+// Fake is a deterministic backend for tests and offline development. It answers with the configured
+// responses in turn, or echoes the last user message when there are none. Its embeddings hash words into
+// a fixed number of buckets, so texts sharing words are similar, which is enough to exercise search.
+type Fake struct {
+	mu        sync.Mutex
+	responses []string
+	calls     int
+}
+
+// NewFake creates a fake answering with responses in turn
+func NewFake(responses ...string) *Fake {
+	return &Fake{responses: responses}
+}
+
+func (f *Fake) Provider() string       { return ProviderFake }
+func (f *Fake) Model() string          { return "fake" }
+func (f *Fake) EmbeddingModel() string { return "fake-embedding" }
+
+func (f *Fake) Complete(ctx context.Context, prompt string, opts ...Option) (Response, error) {
+	return f.Chat(ctx, []Message{{Role: RoleUser, Content: prompt}}, opts...)
+}
+
+func (f *Fake) Chat(ctx context.Context, messages []Message, opts ...Option) (Response, error) {
+	if err := ctx.Err(); err != nil {
+		return Response{}, err
+	}
+
+	f.mu.Lock()
+	text := ""
+	if len(f.responses) > 0 {
+		text = f.responses[f.calls%len(f.responses)]
+	} else {
+		for _, m := range messages {
+			if m.Role == RoleUser {
+				text = "Echo: " + m.Content
+			}
+		}
+	}
+	f.calls++
+	f.mu.Unlock()
+
+	return Response{Text: text, TokensIn: countMessages(messages), TokensOut: CountTokens(text)}, nil
+}
+
+func (f *Fake) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = fakeEmbedding(text)
+	}
+	return vectors, nil
+}
+
+// Calls reports how many completions were requested
+func (f *Fake) Calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls
+}
+
+func fakeEmbedding(text string) []float32 {
+	v := make([]float32, FakeDimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		h := fnv.New32a()
+		h.Write([]byte(w))
+		v[h.Sum32()%FakeDimensions]++
+	}
+
+	var norm float64
+	for _, x := range v {
+		norm += float64(x * x)
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range v {
+			v[i] = float32(float64(v[i]) / norm)
+		}
+	}
+	return v
+}
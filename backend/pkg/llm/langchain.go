@@ -0,0 +1,160 @@
+package llm
+
+import (
+	"context"
+
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// Default models per provider
+const (
+	DefaultOpenAIModel          = "gpt-4.1-mini"
+	DefaultOpenAIEmbeddingModel = "text-embedding-3-small"
+	DefaultOllamaModel          = "llama3.1"
+	DefaultOllamaEmbeddingModel = "nomic-embed-text"
+)
+
+type embedder interface {
+	CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// langchainClient adapts a langchaingo model; OpenAI and Ollama only differ in construction
+type langchainClient struct {
+	provider string
+	model    string
+	embed    string
+	llm      llms.Model
+	embedder embedder
+	json     llms.CallOption // How the provider is asked for JSON
+}
+
+func newOpenAI(cfg Config) (Client, error) {
+	model := valueOr(cfg.Model, DefaultOpenAIModel)
+	embedModel := valueOr(cfg.EmbeddingModel, DefaultOpenAIEmbeddingModel)
+	opts := []openai.Option{
+		openai.WithModel(model),
+		openai.WithEmbeddingModel(embedModel),
+	}
+	if cfg.APIKey != "" {
+		opts = append(opts, openai.WithToken(cfg.APIKey))
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, openai.WithBaseURL(cfg.BaseURL))
+	}
+	client, err := openai.New(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &langchainClient{
+		provider: ProviderOpenAI,
+		model:    model,
+		embed:    embedModel,
+		llm:      client,
+		embedder: client,
+		json:     llms.WithJSONMode(),
+	}, nil
+}
+
+func newOllama(cfg Config) (Client, error) {
+	model := valueOr(cfg.Model, DefaultOllamaModel)
+	client, err := ollama.New(ollama.WithModel(model), ollama.WithServerURL(cfg.BaseURL))
+	if err != nil {
+		return nil, err
+	}
+	// Ollama embeds with the model it was created for, so embeddings get their own instance
+	embedModel := valueOr(cfg.EmbeddingModel, DefaultOllamaEmbeddingModel)
+	embed, err := ollama.New(ollama.WithModel(embedModel), ollama.WithServerURL(cfg.BaseURL))
+	if err != nil {
+		return nil, err
+	}
+	return &langchainClient{
+		provider: ProviderOllama,
+		model:    model,
+		embed:    embedModel,
+		llm:      client,
+		embedder: embed,
+		json:     llms.WithJSONMode(),
+	}, nil
+}
+
+func (l *langchainClient) Provider() string       { return l.provider }
+func (l *langchainClient) Model() string          { return l.model }
+func (l *langchainClient) EmbeddingModel() string { return l.embed }
+
+func (l *langchainClient) Complete(ctx context.Context, prompt string, opts ...Option) (Response, error) {
+	return l.Chat(ctx, []Message{{Role: RoleUser, Content: prompt}}, opts...)
+}
+
+func (l *langchainClient) Chat(ctx context.Context, messages []Message, opts ...Option) (Response, error) {
+	o := applyOptions(opts)
+	callOpts := []llms.CallOption{llms.WithTemperature(o.Temperature)}
+	if o.MaxTokens > 0 {
+		callOpts = append(callOpts, llms.WithMaxTokens(o.MaxTokens))
+	}
+	if o.JSON {
+		callOpts = append(callOpts, l.json)
+	}
+
+	content := make([]llms.MessageContent, 0, len(messages))
+	for _, m := range messages {
+		role := llms.ChatMessageTypeHuman
+		switch m.Role {
+		case RoleSystem:
+			role = llms.ChatMessageTypeSystem
+		case RoleAssistant:
+			role = llms.ChatMessageTypeAI
+		}
+		content = append(content, llms.TextParts(role, m.Content))
+	}
+
+	resp, err := l.llm.GenerateContent(ctx, content, callOpts...)
+	if err != nil {
+		return Response{}, err
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Content == "" {
+		return Response{}, ErrEmptyResponse
+	}
+
+	choice := resp.Choices[0]
+	out := Response{
+		Text:      choice.Content,
+		TokensIn:  intInfo(choice.GenerationInfo, "PromptTokens"),
+		TokensOut: intInfo(choice.GenerationInfo, "CompletionTokens"),
+	}
+	// Not every server reports usage; estimate rather than record zero
+	if out.TokensIn == 0 {
+		out.TokensIn = countMessages(messages)
+	}
+	if out.TokensOut == 0 {
+		out.TokensOut = CountTokens(out.Text)
+	}
+	return out, nil
+}
+
+func (l *langchainClient) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	return l.embedder.CreateEmbedding(ctx, texts)
+}
+
+func intInfo(info map[string]any, key string) int {
+	switch v := info[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+func valueOr(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,171 @@
+// Package llm is the single entry point to language models. Features talk to a Client, which is backed
+// by OpenAI, a local Ollama server or a deterministic fake, selected with LLM_PROVIDER.
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/faiz-muttaqin/lgs/backend/pkg/util"
+	"github.com/sirupsen/logrus"
+)
+
+// Providers
+const (
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+	ProviderFake   = "fake"
+)
+
+// Message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+var (
+	// ErrNotConfigured is returned when LLM_PROVIDER is empty or the provider failed to initialize
+	ErrNotConfigured = errors.New("no LLM provider is configured")
+	// ErrEmptyResponse is returned when the model answered with nothing
+	ErrEmptyResponse = errors.New("the model returned an empty response")
+)
+
+// Message is one turn of a chat prompt
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Response is a completion with the tokens it cost
+type Response struct {
+	Text      string `json:"text"`
+	TokensIn  int    `json:"tokens_in"`
+	TokensOut int    `json:"tokens_out"`
+}
+
+// Options tune a single completion
+type Options struct {
+	MaxTokens   int
+	Temperature float64
+	JSON        bool // Ask for a JSON answer where the provider supports it
+}
+
+// Option sets a completion option
+type Option func(*Options)
+
+// WithMaxTokens caps the length of the answer
+func WithMaxTokens(n int) Option {
+	return func(o *Options) { o.MaxTokens = n }
+}
+
+// WithTemperature sets the sampling temperature
+func WithTemperature(t float64) Option {
+	return func(o *Options) { o.Temperature = t }
+}
+
+// WithJSON asks for a JSON answer
+func WithJSON() Option {
+	return func(o *Options) { o.JSON = true }
+}
+
+func applyOptions(opts []Option) Options {
+	o := Options{Temperature: 0.7}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Client is implemented by every backend
+type Client interface {
+	// Complete answers a single prompt
+	Complete(ctx context.Context, prompt string, opts ...Option) (Response, error)
+	// Chat answers a conversation, usually a system message followed by user messages
+	Chat(ctx context.Context, messages []Message, opts ...Option) (Response, error)
+	// Embed returns one vector per text
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Provider and Model identify the backend in usage records
+	Provider() string
+	Model() string
+	// EmbeddingModel names the model behind Embed; vectors from different models are not comparable
+	EmbeddingModel() string
+}
+
+// Config selects and configures a backend
+type Config struct {
+	Provider       string
+	Model          string
+	EmbeddingModel string
+	BaseURL        string
+	APIKey         string
+}
+
+// ConfigFromEnv reads LLM_PROVIDER, LLM_MODEL, LLM_EMBEDDING_MODEL, OLLAMA_API_URL and OPENAI_API_KEY.
+// The older OLLLAMA_MODEL and OLLLAMA_API_URL names are still honoured.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Provider:       strings.ToLower(strings.TrimSpace(os.Getenv("LLM_PROVIDER"))),
+		Model:          os.Getenv("LLM_MODEL"),
+		EmbeddingModel: os.Getenv("LLM_EMBEDDING_MODEL"),
+	}
+	switch cfg.Provider {
+	case ProviderOpenAI:
+		cfg.APIKey = os.Getenv("OPENAI_API_KEY")
+		cfg.BaseURL = os.Getenv("OPENAI_BASE_URL")
+	case ProviderOllama:
+		if cfg.Model == "" {
+			cfg.Model = os.Getenv("OLLLAMA_MODEL")
+		}
+		cfg.BaseURL = util.Getenv("OLLAMA_API_URL", util.Getenv("OLLLAMA_API_URL", "http://localhost:11434"))
+	}
+	return cfg
+}
+
+// New creates the backend described by cfg
+func New(cfg Config) (Client, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, ErrNotConfigured
+	case ProviderFake:
+		return NewFake(), nil
+	case ProviderOpenAI:
+		return newOpenAI(cfg)
+	case ProviderOllama:
+		return newOllama(cfg)
+	}
+	return nil, fmt.Errorf("unknown LLM provider %q", cfg.Provider)
+}
+
+var (
+	defaultOnce   sync.Once
+	defaultClient Client
+)
+
+// Default returns the client configured in the environment, or nil when there is none. A provider that
+// fails to initialize is logged and treated as not configured; the rest of the app keeps running.
+func Default() Client {
+	defaultOnce.Do(func() {
+		cfg := ConfigFromEnv()
+		client, err := New(cfg)
+		if err != nil {
+			if !errors.Is(err, ErrNotConfigured) {
+				logrus.Errorf("LLM provider %s is unavailable: %v", cfg.Provider, err)
+			}
+			return
+		}
+		logrus.Infof("LLM provider: %s (%s)", client.Provider(), client.Model())
+		defaultClient = client
+	})
+	return defaultClient
+}
+
+// SetDefault replaces the default client, e.g. with NewFake in tests
+func SetDefault(c Client) {
+	defaultOnce.Do(func() {})
+	defaultClient = c
+}
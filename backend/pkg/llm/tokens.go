@@ -0,0 +1,40 @@
+package llm
+
+import (
+	"sync"
+
+	"github.com/pkoukk/tiktoken-go"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	encoderOnce sync.Once
+	encoder     *tiktoken.Tiktoken
+)
+
+// CountTokens counts the tokens of text with the cl100k_base encoding, which is close enough for budgeting
+// on any provider. Without an encoder (tiktoken downloads its vocabularies on first use, which fails offline)
+// it falls back to an estimate of one token per four bytes.
+func CountTokens(text string) int {
+	encoderOnce.Do(func() {
+		enc, err := tiktoken.GetEncoding("cl100k_base")
+		if err != nil {
+			logrus.Errorf("failed to get encoder, estimating token counts: %v", err)
+			return
+		}
+		encoder = enc
+	})
+	if encoder == nil {
+		return (len(text) + 3) / 4
+	}
+	return len(encoder.Encode(text, nil, nil))
+}
+
+// countMessages estimates the prompt tokens of a chat, including a few tokens of framing per message
+func countMessages(messages []Message) int {
+	total := 0
+	for _, m := range messages {
+		total += CountTokens(m.Content) + 4
+	}
+	return total
+}
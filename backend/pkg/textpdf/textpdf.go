@@ -0,0 +1,231 @@
+// Package textpdf writes simple text-only PDF documents (A4, Helvetica) without external dependencies.
+// It is meant for transcripts and reports, not layout: text is wrapped and paginated, nothing more.
+package textpdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	pageWidth    = 595.0 // A4 in points
+	pageHeight   = 842.0
+	margin       = 50.0
+	bodySize     = 10.0
+	headingSize  = 13.0
+	lineSpacing  = 1.4
+	defaultWidth = 556 // Width of characters missing from the table, in 1/1000 em
+)
+
+// helveticaWidths are the Helvetica advance widths for ASCII 32..126 in 1/1000 em
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space .. /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 .. ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ .. O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P .. _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` .. o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p .. ~
+}
+
+type line struct {
+	text string
+	bold bool
+	size float64
+}
+
+// Document collects lines of text and renders them into pages
+type Document struct {
+	title string
+	lines []line
+}
+
+// New starts a document; the title is stored in the PDF metadata
+func New(title string) *Document {
+	return &Document{title: title}
+}
+
+// Heading adds a bold line
+func (d *Document) Heading(text string) {
+	d.add(text, true, headingSize)
+}
+
+// Text adds a paragraph, wrapped to the page width. Newlines start new lines.
+func (d *Document) Text(text string) {
+	d.add(text, false, bodySize)
+}
+
+// Space adds an empty line
+func (d *Document) Space() {
+	d.lines = append(d.lines, line{size: bodySize})
+}
+
+func (d *Document) add(text string, bold bool, size float64) {
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		for _, l := range wrap(paragraph, size) {
+			d.lines = append(d.lines, line{text: l, bold: bold, size: size})
+		}
+	}
+}
+
+// Bytes renders the document
+func (d *Document) Bytes() []byte {
+	pages := d.paginate()
+
+	// Objects: 1 catalog, 2 page tree, 3 regular font, 4 bold font, 5 info, then a page and a
+	// content stream per page
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+i*2)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /Producer (textpdf) >>", escape(d.title)),
+	)
+	for i, page := range pages {
+		stream := renderPage(page, i+1, len(pages))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, 7+i*2),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func (d *Document) paginate() [][]line {
+	var pages [][]line
+	var page []line
+	y := pageHeight - margin
+	for _, l := range d.lines {
+		h := l.size * lineSpacing
+		if y-h < margin+bodySize*2 && len(page) > 0 { // Leave room for the page number
+			pages = append(pages, page)
+			page, y = nil, pageHeight-margin
+		}
+		page = append(page, l)
+		y -= h
+	}
+	return append(pages, page)
+}
+
+func renderPage(lines []line, number, total int) string {
+	var b strings.Builder
+	y := pageHeight - margin
+	for _, l := range lines {
+		y -= l.size * lineSpacing
+		if l.text == "" {
+			continue
+		}
+		font := "F1"
+		if l.bold {
+			font = "F2"
+		}
+		fmt.Fprintf(&b, "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", font, l.size, margin, y, escape(l.text))
+	}
+	footer := fmt.Sprintf("Page %d of %d", number, total)
+	fmt.Fprintf(&b, "BT /F1 8 Tf %.1f %.1f Td (%s) Tj ET", pageWidth-margin-textWidth(footer, 8), margin/2, footer)
+	return b.String()
+}
+
+// wrap breaks a paragraph into lines that fit the page width, splitting overlong words
+func wrap(paragraph string, size float64) []string {
+	maxWidth := pageWidth - 2*margin
+	words := strings.Fields(paragraph)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	current := ""
+	for _, word := range words {
+		for textWidth(word, size) > maxWidth {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			cut := fitPrefix(word, size, maxWidth)
+			lines = append(lines, word[:cut])
+			word = word[cut:]
+		}
+		candidate := word
+		if current != "" {
+			candidate = current + " " + word
+		}
+		if textWidth(candidate, size) > maxWidth {
+			lines = append(lines, current)
+			candidate = word
+		}
+		current = candidate
+	}
+	return append(lines, current)
+}
+
+func fitPrefix(word string, size, maxWidth float64) int {
+	width := 0.0
+	for i, r := range word {
+		width += runeWidth(r) * size / 1000
+		if width > maxWidth {
+			return max(i, 1)
+		}
+	}
+	return len(word)
+}
+
+func textWidth(s string, size float64) float64 {
+	total := 0.0
+	for _, r := range s {
+		total += runeWidth(r)
+	}
+	return total * size / 1000
+}
+
+func runeWidth(r rune) float64 {
+	if r >= 32 && r <= 126 {
+		return float64(helveticaWidths[r-32])
+	}
+	return defaultWidth
+}
+
+// escape encodes text as a PDF string literal in WinAnsiEncoding; characters outside it become '?'
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok || c < 32 {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			if c > 126 {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	return b.String()
+}
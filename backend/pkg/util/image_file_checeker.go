@@ -4,14 +4,18 @@ import (
 	"bytes"
 	"io"
 	"mime/multipart"
+	"net/http"
+	"strings"
 )
 
 func IsValidImage(file multipart.File) (bool, string) {
 	// Read the first few bytes of the file
-	header := make([]byte, 8)
-	if _, err := file.Read(header); err != nil {
+	header := make([]byte, 12)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
 		return false, ""
 	}
+	header = header[:n]
 
 	// Reset the file reader after reading the header
 	if _, err := file.Seek(0, io.SeekStart); err != nil {
@@ -22,6 +26,10 @@ func IsValidImage(file multipart.File) (bool, string) {
 		return true, "PNG"
 	} else if IsJPG(header) {
 		return true, "JPG"
+	} else if IsGIF(header) {
+		return true, "GIF"
+	} else if IsWebP(header) {
+		return true, "WEBP"
 	}
 
 	return false, ""
@@ -29,10 +37,43 @@ func IsValidImage(file multipart.File) (bool, string) {
 
 func IsPNG(header []byte) bool {
 	pngSignature := []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}
-	return bytes.Equal(header, pngSignature)
+	return bytes.HasPrefix(header, pngSignature)
 }
 
 func IsJPG(header []byte) bool {
 	jpgSignature := []byte{0xFF, 0xD8}
 	return bytes.HasPrefix(header, jpgSignature)
 }
+
+func IsGIF(header []byte) bool {
+	return bytes.HasPrefix(header, []byte("GIF87a")) || bytes.HasPrefix(header, []byte("GIF89a"))
+}
+
+func IsWebP(header []byte) bool {
+	return len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP"))
+}
+
+// DetectMIME sniffs the content type from the file's first 512 bytes, ignoring the client-supplied
+// name and Content-Type. The reader is rewound afterwards.
+func DetectMIME(file io.ReadSeeker) (string, error) {
+	header := make([]byte, 512)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	header = header[:n]
+
+	// http.DetectContentType misses QuickTime (.mov), which phones record by default
+	if len(header) >= 12 && bytes.Equal(header[4:8], []byte("ftyp")) && bytes.Equal(header[8:10], []byte("qt")) {
+		return "video/quicktime", nil
+	}
+
+	mime := http.DetectContentType(header)
+	if i := strings.Index(mime, ";"); i >= 0 {
+		mime = mime[:i]
+	}
+	return mime, nil
+}
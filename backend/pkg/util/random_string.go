@@ -1,6 +1,8 @@
 package util
 
 import (
+	crand "crypto/rand"
+	"encoding/base64"
 	"math/rand"
 	"time"
 )
@@ -70,3 +72,13 @@ func GenerateRandomStringUpperCase(charNum int) string {
 
 	return string(token)
 }
+
+// GenerateSecureToken returns a URL-safe random token built from byteLen bytes of crypto/rand.
+// Use this instead of GenerateRandomString for anything that must be unguessable.
+func GenerateSecureToken(byteLen int) (string, error) {
+	b := make([]byte, byteLen)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
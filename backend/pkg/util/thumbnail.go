@@ -0,0 +1,88 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // Register decoders for image.Decode
+	"image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// ErrImageTooLarge is returned by MakeThumbnail for images with more pixels than allowed
+var ErrImageTooLarge = errors.New("image has too many pixels to decode")
+
+// MakeThumbnail decodes a PNG, JPEG or GIF and returns a JPEG scaled to fit within maxSize x maxSize,
+// along with the original dimensions. Images already smaller than maxSize are re-encoded unscaled.
+// The header is read first: an image declaring more than maxPixels is not decoded, since a small file
+// can claim dimensions that need gigabytes of memory. ErrImageTooLarge still reports its dimensions.
+func MakeThumbnail(r io.ReadSeeker, maxSize, maxPixels int) (thumb []byte, width, height int, err error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > int64(maxPixels) {
+		return nil, cfg.Width, cfg.Height, ErrImageTooLarge
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, 0, 0, err
+	}
+
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	b := src.Bounds()
+	width, height = b.Dx(), b.Dy()
+	tw, th := width, height
+	if tw > maxSize || th > maxSize {
+		if tw >= th {
+			th = max(1, th*maxSize/tw)
+			tw = maxSize
+		} else {
+			tw = max(1, tw*maxSize/th)
+			th = maxSize
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		sy0 := b.Min.Y + y*height/th
+		sy1 := max(sy0+1, b.Min.Y+(y+1)*height/th)
+		for x := 0; x < tw; x++ {
+			sx0 := b.Min.X + x*width/tw
+			sx1 := max(sx0+1, b.Min.X+(x+1)*width/tw)
+			dst.Set(x, y, averageColor(src, sx0, sy0, sx1, sy1))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), width, height, nil
+}
+
+// averageColor box-filters the source block so downscaled thumbnails don't alias
+func averageColor(src image.Image, x0, y0, x1, y1 int) color.RGBA {
+	var r, g, b, a, n uint64
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			cr, cg, cb, ca := src.At(x, y).RGBA()
+			r += uint64(cr)
+			g += uint64(cg)
+			b += uint64(cb)
+			a += uint64(ca)
+			n++
+		}
+	}
+	return color.RGBA{
+		R: uint8(r / n >> 8),
+		G: uint8(g / n >> 8),
+		B: uint8(b / n >> 8),
+		A: uint8(a / n >> 8),
+	}
+}